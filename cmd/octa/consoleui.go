@@ -1,10 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"html/template"
 	"io/fs"
 	"net/http"
-	"strings"
+	"path"
 
 	"octa/internal/config"
 	"octa/internal/handlers"
@@ -14,25 +15,49 @@ import (
 	"octa"
 )
 
-func InitConsoleUI(serve *http.ServeMux) {
-
-	staticContent, _ := fs.Sub(octa.WebAssets, "web/static")
-	fileServer := http.FileServer(http.FS(staticContent))
+// loginAssets are the static files the login page needs before authentication, matched exactly.
+// Everything else under /console/static/ requires a session. web/login.html inlines its script
+// and styles and loads nothing from /console/static/, so the list is empty; add a path here
+// if the login page ever gets its own static file.
+var loginAssets = map[string]bool{}
+
+// isLoginAsset reports whether a static path belongs to the login page. The path is cleaned
+// first: an encoded "login%2F..%2Fdashboard.js" reaches the handler uncleaned, and the file
+// server would resolve it outside the login files.
+func isLoginAsset(p string) bool {
+	return loginAssets[path.Clean(p)]
+}
 
-	// SERVE Static files
-	serve.HandleFunc("GET /console/static/", func(w http.ResponseWriter, r *http.Request) {
+// staticHandler serves /console/static/ from assets: the login page's files to anyone,
+// everything else only with a session.
+func staticHandler(assets fs.FS) http.HandlerFunc {
+	fileServer := http.StripPrefix("/console/static/", http.FileServer(http.FS(assets)))
 
-		if !handlers.IsAuthenticated(r) {
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
-		}
-		if !strings.HasPrefix(r.URL.Path, "/console/static/js/login") && !handlers.IsAuthenticated(r) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The login page loads before there is a session, so its own assets are public
+		if !isLoginAsset(r.URL.Path) && !handlers.IsAuthenticated(r) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
 
-		http.StripPrefix("/console/static/", fileServer).ServeHTTP(w, r)
-	})
+		fileServer.ServeHTTP(w, r)
+	}
+}
+
+// Console pages are parsed once from the embedded assets by InitConsoleUI.
+var (
+	loginTemplate     *template.Template
+	dashboardTemplate *template.Template
+)
+
+func InitConsoleUI(serve *http.ServeMux) {
+	loginTemplate = mustParseTemplate("web/login.html")
+	dashboardTemplate = mustParseTemplate("web/dashboard.html")
+
+	staticContent, _ := fs.Sub(octa.WebAssets, "web/static")
+
+	// SERVE Static files
+	serve.HandleFunc("GET /console/static/", staticHandler(staticContent))
 
 	// AUTHENTICATION ROUTES
 	serve.HandleFunc("GET /console/login", handleLoginPage)
@@ -47,9 +72,41 @@ func InitConsoleUI(serve *http.ServeMux) {
 	// GET stats
 	serve.HandleFunc("GET /console/api/stats", handlers.AuthMiddleware(handlers.GetStats))
 
+	// GET cache, database, runtime and config details in one view
+	serve.HandleFunc("GET /console/api/system", handlers.AuthMiddleware(handlers.GetSystem))
+
+	// GET live traffic (rps, error rate, latency percentiles)
+	serve.HandleFunc("GET /console/api/traffic", handlers.AuthMiddleware(handlers.GetTraffic))
+
 	// GET Assets
 	serve.HandleFunc("GET /console/api/assets", handlers.AuthMiddleware(handlers.ListAssets))
 
+	// GET folder tree derived from key prefixes
+	serve.HandleFunc("GET /console/api/folders", handlers.AuthMiddleware(handlers.ListFolders))
+
+	// POST bulk rename keys by prefix (e.g. nature/ -> landscapes/)
+	serve.HandleFunc("POST /console/api/assets/move", handlers.AuthMiddleware(handlers.MoveAssets))
+
+	// POST replace keys of many assets in one transaction
+	serve.HandleFunc("POST /console/api/assets/bulk-keys", handlers.AuthMiddleware(handlers.BulkUpdateAssetKeys))
+
+	// POST re-encode a stored image with current/new processing options
+	serve.HandleFunc("POST /console/api/assets/{id}/reprocess", handlers.AuthMiddleware(handlers.ReprocessAsset))
+
+	// Background job: reprocess every stored image to a target format (start / progress / cancel)
+	serve.HandleFunc("POST /console/api/jobs/reprocess", handlers.AuthMiddleware(handlers.StartReprocessJob))
+	serve.HandleFunc("GET /console/api/jobs/reprocess", handlers.AuthMiddleware(handlers.GetReprocessJob))
+	serve.HandleFunc("DELETE /console/api/jobs/reprocess", handlers.AuthMiddleware(handlers.CancelReprocessJob))
+
+	// Settings snapshot: export as JSON / import (saved to consoleui.settings_file, applied on restart)
+	serve.HandleFunc("GET /console/api/settings/export", handlers.AuthMiddleware(handlers.ExportSettings))
+	serve.HandleFunc("POST /console/api/settings/import", handlers.AuthMiddleware(handlers.ImportSettings))
+
+	// Database maintenance on demand: VACUUM (background, GET reports the last run) and WAL checkpoint
+	serve.HandleFunc("POST /console/api/db/vacuum", handlers.AuthMiddleware(handlers.VacuumDB))
+	serve.HandleFunc("GET /console/api/db/vacuum", handlers.AuthMiddleware(handlers.GetVacuumStatus))
+	serve.HandleFunc("POST /console/api/db/checkpoint", handlers.AuthMiddleware(handlers.CheckpointDB))
+
 	// GET backup sqlite database
 	serve.HandleFunc("GET /console/api/backup", handlers.AuthMiddleware(handlers.BackupHandler))
 
@@ -58,6 +115,9 @@ func InitConsoleUI(serve *http.ServeMux) {
 
 	// PUT update asset keys
 	serve.HandleFunc("PUT /console/api/assets/{id}", handlers.AuthMiddleware(handlers.UpdateAssetKeys))
+
+	// PATCH add/remove individual asset keys
+	serve.HandleFunc("PATCH /console/api/assets/{id}", handlers.AuthMiddleware(handlers.PatchAssetKeys))
 }
 
 // landing page
@@ -99,24 +159,33 @@ func handleLoginPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	renderTemplate(w, "web/login.html")
+	renderTemplate(w, loginTemplate)
 }
 
 func handleDashboard(w http.ResponseWriter, r *http.Request) {
-	renderTemplate(w, "web/dashboard.html")
+	renderTemplate(w, dashboardTemplate)
 }
 
-func renderTemplate(w http.ResponseWriter, path string) {
+// mustParseTemplate parses an embedded page. The files are compiled into the binary, so a
+// parse error is a build problem and stops startup instead of failing every request.
+func mustParseTemplate(path string) *template.Template {
 	tmpl, err := template.ParseFS(octa.WebAssets, path)
 	if err != nil {
-		
-		logger.LogError("Template Error: %v", err)
-		http.Error(w, "Internal Server Error", 500)
-		return
+		logger.LogFatal("Template Error (%s): %v", path, err)
 	}
+	return tmpl
+}
 
+// renderTemplate executes a pre-parsed page into a buffer first, so an execution error
+// becomes a logged 500 instead of a half-written page with a 200 status.
+func renderTemplate(w http.ResponseWriter, tmpl *template.Template) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]string{"BaseURL": config.AppConfig.GetBaseUrl()}); err != nil {
+		logger.LogError("Template Error (%s): %v", tmpl.Name(), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
 
-	baseURL := config.AppConfig.GetBaseUrl()
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	tmpl.Execute(w, map[string]string{"BaseURL": baseURL})
+	w.Write(buf.Bytes())
 }
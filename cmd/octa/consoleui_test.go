@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+// withLoginAssets swaps the allowlist for one test; the shipped list is empty.
+func withLoginAssets(t *testing.T, paths ...string) {
+	t.Helper()
+	saved := loginAssets
+	loginAssets = map[string]bool{}
+	for _, p := range paths {
+		loginAssets[p] = true
+	}
+	t.Cleanup(func() { loginAssets = saved })
+}
+
+func TestIsLoginAsset(t *testing.T) {
+	withLoginAssets(t, "/console/static/js/login.js")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/console/static/js/login.js", true},
+		{"/console/static/js/dashboard.js", false},
+		{"/console/static/js/login-anything.js", false},
+		{"/console/static/js/login.js.map", false},
+		{"/console/static/js/login.js/../dashboard.js", false},
+		{"/console/static/js/../js/login.js", true},
+	}
+	for _, tt := range tests {
+		if got := isLoginAsset(tt.path); got != tt.want {
+			t.Errorf("isLoginAsset(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// Without a session cookie only the allowlisted files are served.
+func TestStaticHandlerUnauthenticated(t *testing.T) {
+	assets := fstest.MapFS{
+		"js/login.js":          {Data: []byte("// login")},
+		"js/login-anything.js": {Data: []byte("// not a login file")},
+		"js/dashboard.js":      {Data: []byte("// dashboard")},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /console/static/", staticHandler(assets))
+
+	tests := []struct {
+		name, target string
+		wantCode     int
+		wantBody     string
+	}{
+		{"login asset", "/console/static/js/login.js", http.StatusOK, "// login"},
+		{"dashboard asset", "/console/static/js/dashboard.js", http.StatusForbidden, ""},
+		{"login-like name", "/console/static/js/login-anything.js", http.StatusForbidden, ""},
+		{"encoded traversal", "/console/static/js/login.js%2F..%2Fdashboard.js", http.StatusForbidden, ""},
+	}
+
+	t.Run("shipped allowlist", func(t *testing.T) {
+		// The login page loads no static files, so nothing is public
+		for _, tt := range tests {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("GET %s: status = %d, want 403", tt.target, rec.Code)
+			}
+		}
+	})
+
+	withLoginAssets(t, "/console/static/js/login.js")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("GET %s: status = %d, want %d", tt.target, rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("GET %s: body = %q, want %q", tt.target, rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
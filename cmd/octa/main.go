@@ -12,6 +12,7 @@ import (
 	"octa/internal/database"
 	"octa/internal/handlers"
 	"octa/internal/middleware"
+	"octa/internal/moderation"
 	"octa/pkg/cache"
 	"octa/pkg/logger"
 	"octa/pkg/utils"
@@ -41,17 +42,40 @@ if startupMessageActive != "false" {
 	database.InitDB()
 	go database.StartCleaner()
 
+	// Upload Moderation (optional)
+	moderation.Init()
+
 	// App Uptime
 	appinfo.StartTime = time.Now()
 
 	// Cache
 	appCache := cache.New()
 	handlers.SetCache(appCache)
+	go handlers.PreloadCache(config.AppConfig.Cache.PreloadCount)
 
-	if err := utils.InitFonts("fonts/Inter_28pt-SemiBold.ttf"); err != nil {
+	if err := utils.InitFonts(config.AppConfig.Image.FontPath); err != nil {
 		// log.Printf("Warning: Font loading failed, using fallback. Error: %v", err)
 		logger.LogWarn("Warning: Font loading failed, using fallback. Error: %v", err)
 	}
+	utils.SetDefaultFontWeight(config.AppConfig.Image.FontWeight)
+	for name, path := range config.AppConfig.Image.FontWeights {
+		if err := utils.RegisterFontWeight(config.FontWeightValues[name], path); err != nil {
+			logger.LogWarn("Font weight '%s' not loaded, ?weight=%s uses the nearest available: %v", name, name, err)
+		}
+	}
+	for _, f := range config.AppConfig.Image.Fonts {
+		if err := utils.RegisterFont(f.Name, f.Path); err != nil {
+			logger.LogWarn("Font '%s' not loaded, ?font=%s uses the default font: %v", f.Name, f.Name, err)
+		}
+	}
+
+	// Watermark (loaded once, after fonts for text marks)
+	if err := utils.InitWatermark(config.AppConfig.Image.Watermark); err != nil {
+		logger.LogWarn("Watermark disabled: %v", err)
+	}
+
+	// Diagnostics: fail fast on critical problems, warn on soft ones
+	runStartupChecks(startupMessageActive != "false")
 
 	mux := http.NewServeMux()
 
@@ -62,30 +86,41 @@ if startupMessageActive != "false" {
 	// }
 
 	// Public Avatar & Assets Routes
+	// Note: "GET" patterns also match HEAD requests (Go 1.22+ ServeMux),
+	// so browsers/proxies can probe existence & ETag without downloading the image.
 	mux.HandleFunc("GET /avatar/{seed}", handlers.ServeDirectAvatar)              // /avatar/octa
 	mux.HandleFunc("GET /u/{key...}", handlers.ServeUserAvatar)                   // /u/admin
 	mux.HandleFunc("GET /avatar/github/{username}", handlers.GithubAvatarHandler) // /avatar/github/octocat
+	mux.HandleFunc("GET /avatar/{seed}/{action}", handlers.ServeAvatarAction)     // /avatar/octa/palette, /avatar/octa/srcset
+	mux.HandleFunc("GET /montage", handlers.ServeMontage)                         // /montage?seeds=a,b,c&cols=3 (not under /avatar/, "montage" stays a valid seed)
 
 	// Upload Routews
 	mux.HandleFunc("POST /upload", handlers.UploadHandler)
 	mux.HandleFunc("DELETE /upload/delete", handlers.DeleteAPIHandler)
 
-	if config.AppConfig.Cache.Enabled {
+	if config.AppConfig.ConsoleUI.Enabled {
 		InitConsoleUI(mux)
 	}
 
-	finalHandler := middleware.RateLimitMiddleware(middleware.CorsMiddleware(middleware.LoggerMiddleware(mux)))
+	finalHandler := middleware.ResponseHeadersMiddleware(middleware.RateLimitMiddleware(middleware.CorsMiddleware(middleware.LoggerMiddleware(mux))))
 
 	// FOR BENCHMARK
 	// finalHandler := middleware.CorsMiddleware(middleware.LoggerMiddleware(mux))
 
+	// Probes bypass the middleware chain: load balancers poll them often and must never be
+	// rate limited, logged per request or blocked by CORS.
+	root := http.NewServeMux()
+	root.HandleFunc("GET /health", handlers.HealthHandler)
+	root.HandleFunc("GET /ready", handlers.ReadyHandler)
+	root.Handle("/", finalHandler)
+
 	port := config.AppConfig.Server.Port
 
 	baseURL := config.AppConfig.GetBaseUrl()
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
-		Handler:      finalHandler,
+		Handler:      root,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"octa/internal/config"
+	"octa/internal/database"
+	"octa/pkg/logger"
+	"octa/pkg/utils"
+)
+
+type checkStatus int
+
+const (
+	checkOK checkStatus = iota
+	checkWarn
+	checkFail // Critical: the server refuses to start
+)
+
+// startupCheck is one row of the startup diagnostics table.
+type startupCheck struct {
+	Name   string
+	Status checkStatus
+	Detail string
+}
+
+// runStartupChecks verifies the environment once everything is initialized, prints a summary
+// table and exits on critical problems instead of letting them surface on the first request.
+func runStartupChecks(showTable bool) {
+	checks := []startupCheck{
+		checkDatabase(),
+		checkKeyIndexes(),
+		checkDiskSpace(),
+		checkFont(),
+		checkUploadSecret(),
+		checkCache(),
+	}
+
+	if showTable {
+		printStartupChecks(checks)
+	}
+
+	failed := 0
+	for _, c := range checks {
+		switch c.Status {
+		case checkWarn:
+			logger.LogWarn("Startup check '%s': %s", c.Name, c.Detail)
+		case checkFail:
+			logger.LogError("Startup check '%s' failed: %s", c.Name, c.Detail)
+			failed++
+		}
+	}
+	if failed > 0 {
+		logger.LogFatal("%d critical startup check(s) failed, refusing to start", failed)
+	}
+}
+
+// checkDatabase makes sure the DB answers and its directory accepts new files (WAL, -shm, backups).
+func checkDatabase() startupCheck {
+	c := startupCheck{Name: "Database"}
+
+	sqlDB, err := database.DB.DB()
+	if err == nil {
+		err = sqlDB.Ping()
+	}
+	if err != nil {
+		c.Status, c.Detail = checkFail, fmt.Sprintf("not reachable: %v", err)
+		return c
+	}
+
+	dir := filepath.Dir(config.AppConfig.Database.Path)
+	probe, err := os.CreateTemp(dir, ".octa-write-check-*")
+	if err != nil {
+		c.Status, c.Detail = checkFail, fmt.Sprintf("directory %s is not writable: %v", dir, err)
+		return c
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	c.Detail = config.AppConfig.Database.Path + " (writable)"
+	return c
+}
+
+// checkKeyIndexes confirms avatar lookups and console searches seek the key index instead of scanning.
+func checkKeyIndexes() startupCheck {
+	c := startupCheck{Name: "Key indexes"}
+
+	problems, err := database.UnindexedKeyQueries(database.ReadDB)
+	switch {
+	case err != nil:
+		c.Status, c.Detail = checkWarn, fmt.Sprintf("query plan unavailable: %v", err)
+	case len(problems) > 0:
+		c.Status, c.Detail = checkWarn, "full table scan: "+strings.Join(problems, "; ")
+	default:
+		c.Detail = "lookup and search use indexes"
+	}
+	return c
+}
+
+// checkDiskSpace warns when the disk could fill up before the DB reaches database.max_size.
+func checkDiskSpace() startupCheck {
+	c := startupCheck{Name: "Disk space"}
+
+	free, ok := freeDiskBytes(filepath.Dir(config.AppConfig.Database.Path))
+	if !ok {
+		c.Status, c.Detail = checkWarn, "free space unknown on this platform"
+		return c
+	}
+
+	limit := utils.SizeToBytes(config.AppConfig.Database.MaxSize, 2*1024*1024*1024)
+	var used int64
+	if info, err := os.Stat(config.AppConfig.Database.Path); err == nil {
+		used = info.Size()
+	}
+
+	c.Detail = fmt.Sprintf("%s free, database.max_size %s", utils.FormatBytes(int64(free)), config.AppConfig.Database.MaxSize)
+	if growth := limit - used; growth > 0 && int64(free) < growth {
+		c.Status = checkWarn
+		c.Detail = fmt.Sprintf("only %s free, the database may still grow by %s (database.max_size %s)",
+			utils.FormatBytes(int64(free)), utils.FormatBytes(growth), config.AppConfig.Database.MaxSize)
+	}
+	return c
+}
+
+func checkFont() startupCheck {
+	if !utils.FontLoaded() {
+		return startupCheck{Name: "Font", Status: checkWarn, Detail: config.AppConfig.Image.FontPath + " not loaded: PNG avatars are rendered without initials"}
+	}
+	detail := config.AppConfig.Image.FontPath
+	var missing []string
+	for _, f := range config.AppConfig.Image.Fonts {
+		if !utils.FontRegistered(f.Name) {
+			missing = append(missing, f.Name)
+		}
+	}
+	if n := len(config.AppConfig.Image.Fonts); n > 0 {
+		detail += fmt.Sprintf(" (+%d named)", n-len(missing))
+	}
+	if len(missing) > 0 {
+		return startupCheck{Name: "Font", Status: checkWarn, Detail: detail + ", not loaded: " + strings.Join(missing, ", ")}
+	}
+	return startupCheck{Name: "Font", Detail: detail}
+}
+
+// checkUploadSecret only warns: Validate already rejects a default secret in production.
+func checkUploadSecret() startupCheck {
+	switch config.AppConfig.Security.UploadSecret {
+	case "", "secret", "CHANGE_THIS_IN_ENV":
+		return startupCheck{Name: "Upload secret", Status: checkWarn, Detail: "default or empty, anyone can upload"}
+	}
+	return startupCheck{Name: "Upload secret", Detail: "set"}
+}
+
+func checkCache() startupCheck {
+	if !config.AppConfig.Cache.Enabled {
+		return startupCheck{Name: "Cache", Status: checkWarn, Detail: "disabled: every request renders or hits the database"}
+	}
+	return startupCheck{Name: "Cache", Detail: fmt.Sprintf("enabled (%d MB, %s)", config.AppConfig.Cache.MaxCapacity, config.AppConfig.Cache.EvictionPolicy)}
+}
+
+func printStartupChecks(checks []startupCheck) {
+	labels := map[checkStatus]string{
+		checkOK:   color.New(color.FgGreen, color.Bold).Sprint("OK  "),
+		checkWarn: color.New(color.FgYellow, color.Bold).Sprint("WARN"),
+		checkFail: color.New(color.FgRed, color.Bold).Sprint("FAIL"),
+	}
+	dim := color.New(color.FgHiBlack).SprintFunc()
+
+	fmt.Println()
+	fmt.Printf("   %s\n", color.New(color.FgHiCyan, color.Bold).Sprint("Startup checks"))
+	for _, c := range checks {
+		fmt.Printf("   %s  %-14s %s\n", labels[c.Status], c.Name, dim(c.Detail))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,133 @@
+package appinfo
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// TrafficWindow: Seconds of per-second traffic kept in memory (the longest window a snapshot can cover).
+	TrafficWindow = 300
+
+	// latencyBuckets: Histogram bucket i counts requests faster than latencyBase << i (100µs ... ~7min).
+	latencyBuckets = 22
+	latencyBase    = 100 * time.Microsecond
+)
+
+// trafficBucket aggregates one second. Writers only touch atomics, so the hot path never blocks.
+type trafficBucket struct {
+	sec          atomic.Int64 // Unix second this bucket currently holds
+	requests     atomic.Uint64
+	serverErrors atomic.Uint64 // 5xx
+	clientErrors atomic.Uint64 // 4xx
+	latency      [latencyBuckets]atomic.Uint64
+}
+
+var trafficRing [TrafficWindow]trafficBucket
+
+// RecordRequest adds one finished request to the current second.
+// When a bucket is recycled a few concurrent samples at the boundary may be lost; the numbers are approximate by design.
+func RecordRequest(status int, d time.Duration) {
+	now := time.Now().Unix()
+	b := &trafficRing[now%TrafficWindow]
+
+	if old := b.sec.Load(); old != now && b.sec.CompareAndSwap(old, now) {
+		b.requests.Store(0)
+		b.serverErrors.Store(0)
+		b.clientErrors.Store(0)
+		for i := range b.latency {
+			b.latency[i].Store(0)
+		}
+	}
+
+	b.requests.Add(1)
+	switch {
+	case status >= 500:
+		b.serverErrors.Add(1)
+	case status >= 400:
+		b.clientErrors.Add(1)
+	}
+	b.latency[latencyBucket(d)].Add(1)
+}
+
+func latencyBucket(d time.Duration) int {
+	if d < latencyBase {
+		return 0
+	}
+	return min(bits.Len64(uint64(d/latencyBase)), latencyBuckets-1)
+}
+
+// TrafficPoint is one second of the series used for charts.
+type TrafficPoint struct {
+	Time     int64  `json:"t"` // Unix seconds
+	Requests uint64 `json:"requests"`
+	Errors   uint64 `json:"errors"` // 5xx
+}
+
+// TrafficSnapshot summarizes the last WindowSeconds seconds.
+type TrafficSnapshot struct {
+	WindowSeconds   int            `json:"window_seconds"`
+	Requests        uint64         `json:"requests"`
+	RPS             float64        `json:"rps"`
+	ErrorRate       float64        `json:"error_rate"`        // 5xx share, 0-1
+	ClientErrorRate float64        `json:"client_error_rate"` // 4xx share, 0-1
+	P50Ms           float64        `json:"p50_ms"`            // Histogram upper bound, not exact
+	P95Ms           float64        `json:"p95_ms"`
+	Series          []TrafficPoint `json:"series"` // Oldest first, one point per second
+}
+
+// Traffic aggregates the last window seconds (clamped to 1..TrafficWindow).
+func Traffic(window int) TrafficSnapshot {
+	window = max(1, min(window, TrafficWindow))
+	now := time.Now().Unix()
+
+	snap := TrafficSnapshot{WindowSeconds: window, Series: make([]TrafficPoint, 0, window)}
+	var serverErrors, clientErrors uint64
+	var hist [latencyBuckets]uint64
+
+	for sec := now - int64(window) + 1; sec <= now; sec++ {
+		point := TrafficPoint{Time: sec}
+
+		b := &trafficRing[sec%TrafficWindow]
+		if b.sec.Load() == sec {
+			point.Requests = b.requests.Load()
+			point.Errors = b.serverErrors.Load()
+			clientErrors += b.clientErrors.Load()
+			for i := range hist {
+				hist[i] += b.latency[i].Load()
+			}
+		}
+
+		snap.Requests += point.Requests
+		serverErrors += point.Errors
+		snap.Series = append(snap.Series, point)
+	}
+
+	snap.RPS = float64(snap.Requests) / float64(window)
+	if snap.Requests > 0 {
+		snap.ErrorRate = float64(serverErrors) / float64(snap.Requests)
+		snap.ClientErrorRate = float64(clientErrors) / float64(snap.Requests)
+		snap.P50Ms = latencyQuantile(hist, 0.50)
+		snap.P95Ms = latencyQuantile(hist, 0.95)
+	}
+	return snap
+}
+
+// latencyQuantile returns the upper bound (ms) of the bucket holding quantile q.
+func latencyQuantile(hist [latencyBuckets]uint64, q float64) float64 {
+	var total uint64
+	for _, c := range hist {
+		total += c
+	}
+
+	target := uint64(q*float64(total) + 0.5)
+	var seen uint64
+	for i, c := range hist {
+		seen += c
+		if seen >= target && seen > 0 {
+			return float64(latencyBase<<i) / float64(time.Millisecond)
+		}
+	}
+	return float64(latencyBase<<(latencyBuckets-1)) / float64(time.Millisecond)
+}
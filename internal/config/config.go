@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"os"
 
 	"strings"
 	"time"
@@ -14,6 +17,9 @@ import (
 
 var AppConfig *Config
 
+// MaxConsolePageSize is the hard upper bound for consoleui.max_page_size.
+const MaxConsolePageSize = 1000
+
 func (c *Config) GetBaseUrl() string {
 	if c.BaseURL != "" {
 		return strings.TrimRight(c.BaseURL, "/")
@@ -58,6 +64,19 @@ func Load() {
 		}
 	}
 
+	// Settings imported from the console (see SaveSettings) override config.yaml; env vars still win
+	if path := v.GetString("consoleui.settings_file"); path != "" {
+		if f, err := os.Open(path); err == nil {
+			v.SetConfigType("json")
+			if err := v.MergeConfig(f); err != nil {
+				logger.LogWarn("Settings file %s unreadable, ignored: %v", path, err)
+			} else {
+				logger.LogInfo("Settings loaded from %s", path)
+			}
+			f.Close()
+		}
+	}
+
 	if err := v.Unmarshal(&AppConfig); err != nil {
 		log.Fatalf("[CRITICAL] Error: Failed to parse configuration: %v", err)
 	}
@@ -88,28 +107,64 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.env", "development")
 
 	// Image Engine
-	v.SetDefault("image.size", 256)
+	v.SetDefault("image.default_size", 256)
 	v.SetDefault("image.quality", 80)
+	v.SetDefault("image.font_path", "fonts/Inter_28pt-SemiBold.ttf")
+	v.SetDefault("image.font_weight", 600)
+	v.SetDefault("image.upload_format", "jpeg")
+	v.SetDefault("image.allow_avif", false)
 	v.SetDefault("image.max_upload_size", "5MB")
 	v.SetDefault("image.max_key_limit", 7)
+	v.SetDefault("image.serve_webp", false)
+	v.SetDefault("image.size_step", 0)
+	v.SetDefault("image.max_concurrent_generation", 0)
+	v.SetDefault("image.generation_timeout", "3s")
+	v.SetDefault("image.coalesce_timeout", "10s")
+	v.SetDefault("image.github_max_concurrent", 8)
+	v.SetDefault("image.github_queue_timeout", "2s")
+	v.SetDefault("image.degrade_under_load", false)
+	v.SetDefault("image.degrade_threshold", 0.8)
+	v.SetDefault("image.moderation.enabled", false)
+	v.SetDefault("image.moderation.timeout", "5s")
+	v.SetDefault("image.moderation.fail_open", false)
+	v.SetDefault("image.watermark.enabled", false)
+	v.SetDefault("image.watermark.position", "bottom-right")
+	v.SetDefault("image.watermark.opacity", 0.5)
+	v.SetDefault("image.watermark.scale", 0.2)
 
 	// Caching
 	v.SetDefault("cache.enabled", true)
 	v.SetDefault("cache.max_capacity", 100) // 100 MB
 	v.SetDefault("cache.ttl", "30m")
+	v.SetDefault("cache.generated_ttl", "24h")
+	v.SetDefault("cache.eviction_policy", "lru")
+	v.SetDefault("cache.backend", "memory")
+	v.SetDefault("cache.preload_count", 0)
 
 	// Security & Limits
+	v.SetDefault("security.public_image_cors", true)
 	v.SetDefault("security.rate_limit.enabled", true)
 	v.SetDefault("security.rate_limit.requests", 20)
 	v.SetDefault("security.rate_limit.window", "1s")
 	v.SetDefault("security.rate_limit.burst", 50)
+	v.SetDefault("security.rate_limit.max_visitors", 100000)
+	v.SetDefault("security.login_rate_limit.rate", 1.0)
+	v.SetDefault("security.login_rate_limit.burst", 10)
+	v.SetDefault("security.login_rate_limit.failure_delay", "500ms")
 
 	// Console UI
 	v.SetDefault("consoleui.enabled", true)
+	v.SetDefault("consoleui.max_page_size", 100)
+	v.SetDefault("consoleui.settings_file", "data/settings.json")
 
 	// Database
 	v.SetDefault("database.max_size", "2GB")
 	v.SetDefault("database.prune_interval", "5m")
+	v.SetDefault("database.max_concurrent_writes", 10)
+	v.SetDefault("database.write_queue_timeout", "5s")
+	v.SetDefault("database.read_pool_size", 4)
+	v.SetDefault("database.prepare_stmt", true)
+	v.SetDefault("database.prepare_stmt_max_size", 256)
 }
 
 func (c *Config) Validate() error {
@@ -125,12 +180,164 @@ func (c *Config) Validate() error {
 	if _, err := time.ParseDuration(c.Cache.TTL); err != nil {
 		return fmt.Errorf("invalid cache.ttl format '%s': %v", c.Cache.TTL, err)
 	}
+	if _, err := time.ParseDuration(c.Cache.GeneratedTTL); err != nil {
+		return fmt.Errorf("invalid cache.generated_ttl format '%s': %v", c.Cache.GeneratedTTL, err)
+	}
+
+	// Cache: Eviction Policy Check
+	switch strings.ToLower(c.Cache.EvictionPolicy) {
+	case "ttl", "lru", "lfu", "fifo":
+	default:
+		return fmt.Errorf("invalid cache.eviction_policy '%s': use ttl, lru, lfu or fifo", c.Cache.EvictionPolicy)
+	}
+
+	// Cache: Preload Check
+	if c.Cache.PreloadCount < 0 {
+		return fmt.Errorf("invalid cache.preload_count '%d': must be 0 or more", c.Cache.PreloadCount)
+	}
+
+	// Cache: Backend Check (the Redis URL is only required when Redis is used)
+	switch strings.ToLower(c.Cache.Backend) {
+	case "memory":
+	case "redis":
+		u, err := url.Parse(c.Cache.RedisURL)
+		if c.Cache.RedisURL == "" || err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+			return fmt.Errorf("cache.redis_url must be a redis:// or rediss:// URL when cache.backend is 'redis'")
+		}
+	default:
+		return fmt.Errorf("invalid cache.backend '%s': use memory or redis", c.Cache.Backend)
+	}
+
+	// Database: Write Concurrency Check
+	if c.Database.MaxConcurrentWrites < 1 {
+		return fmt.Errorf("invalid database.max_concurrent_writes '%d': must be at least 1", c.Database.MaxConcurrentWrites)
+	}
+
+	// Database: Read Pool Check
+	if c.Database.ReadPoolSize < 0 {
+		return fmt.Errorf("invalid database.read_pool_size '%d': must be 0 or more", c.Database.ReadPoolSize)
+	}
+
+	// Database: Prepared Statement Cache Check
+	if c.Database.PrepareStmt && c.Database.PrepareStmtMaxSize < 1 {
+		return fmt.Errorf("invalid database.prepare_stmt_max_size '%d': must be at least 1", c.Database.PrepareStmtMaxSize)
+	}
+
+	// Database: Write Queue Timeout Parsing Check
+	if _, err := time.ParseDuration(c.Database.WriteQueueTimeout); err != nil {
+		return fmt.Errorf("invalid database.write_queue_timeout format '%s': %v", c.Database.WriteQueueTimeout, err)
+	}
+
+	// Image: Quality Range Check
+	if c.Image.Quality < 1 || c.Image.Quality > 100 {
+		return fmt.Errorf("invalid image.quality '%d': must be between 1 and 100", c.Image.Quality)
+	}
+
+	// Image: Upload Format Check
+	switch c.Image.UploadFormat {
+	case "jpeg", "png", "webp":
+	default:
+		return fmt.Errorf("invalid image.upload_format '%s': use jpeg, png or webp", c.Image.UploadFormat)
+	}
+
+	// Image: Generation Timeout Parsing Check
+	if _, err := time.ParseDuration(c.Image.GenerationTimeout); err != nil {
+		return fmt.Errorf("invalid image.generation_timeout format '%s': %v", c.Image.GenerationTimeout, err)
+	}
+
+	// Image: Coalesce Timeout Parsing Check
+	if _, err := time.ParseDuration(c.Image.CoalesceTimeout); err != nil {
+		return fmt.Errorf("invalid image.coalesce_timeout format '%s': %v", c.Image.CoalesceTimeout, err)
+	}
+
+	// Image: GitHub Queue Timeout Parsing Check
+	if _, err := time.ParseDuration(c.Image.GitHubQueueTimeout); err != nil {
+		return fmt.Errorf("invalid image.github_queue_timeout format '%s': %v", c.Image.GitHubQueueTimeout, err)
+	}
+
+	// Image: Moderation Check
+	if c.Image.Moderation.Enabled {
+		if c.Image.Moderation.Endpoint == "" {
+			return fmt.Errorf("image.moderation is enabled but image.moderation.endpoint is empty")
+		}
+		if _, err := time.ParseDuration(c.Image.Moderation.Timeout); err != nil {
+			return fmt.Errorf("invalid image.moderation.timeout format '%s': %v", c.Image.Moderation.Timeout, err)
+		}
+	}
+
+	// Image: Watermark Check
+	if wm := c.Image.Watermark; wm.Image != "" || wm.Text != "" {
+		if wm.Opacity <= 0 || wm.Opacity > 1 {
+			return fmt.Errorf("invalid image.watermark.opacity '%v': must be between 0 and 1", wm.Opacity)
+		}
+		if wm.Scale <= 0 || wm.Scale > 1 {
+			return fmt.Errorf("invalid image.watermark.scale '%v': must be between 0 and 1", wm.Scale)
+		}
+		switch wm.Position {
+		case "top-left", "top-right", "bottom-left", "bottom-right", "center":
+		default:
+			return fmt.Errorf("invalid image.watermark.position '%s'", wm.Position)
+		}
+	}
+
+	// Image: Named Fonts Check
+	seenFonts := map[string]bool{}
+	for _, f := range c.Image.Fonts {
+		if !validFontName(f.Name) {
+			return fmt.Errorf("invalid image.fonts name '%s': use lowercase letters, digits, '-' and '_'", f.Name)
+		}
+		if seenFonts[f.Name] {
+			return fmt.Errorf("duplicate image.fonts name '%s'", f.Name)
+		}
+		if f.Path == "" {
+			return fmt.Errorf("image.fonts '%s' has no path", f.Name)
+		}
+		seenFonts[f.Name] = true
+	}
+
+	// Image: Font Weights Check
+	if c.Image.FontWeight < 100 || c.Image.FontWeight > 900 {
+		return fmt.Errorf("invalid image.font_weight '%d': must be between 100 and 900", c.Image.FontWeight)
+	}
+	for name, path := range c.Image.FontWeights {
+		if _, ok := FontWeightValues[name]; !ok {
+			return fmt.Errorf("invalid image.font_weights key '%s': use light, regular, medium, semibold or bold", name)
+		}
+		if path == "" {
+			return fmt.Errorf("image.font_weights '%s' has no path", name)
+		}
+	}
+
+	// Server: Response Headers Check
+	for name := range c.Server.ResponseHeaders {
+		if IsReservedResponseHeader(name) {
+			return fmt.Errorf("server.response_headers cannot set '%s': it is managed per response", name)
+		}
+	}
 
 	// RateLimit: Window Parsing Check
 	if _, err := time.ParseDuration(c.Security.RateLimit.Window); err != nil {
 		return fmt.Errorf("invalid rate_limit.window format '%s': %v", c.Security.RateLimit.Window, err)
 	}
 
+	// RateLimit: Visitor Cap Check (it bounds the limiter's memory, so it can't be unlimited; 0 = default)
+	if c.Security.RateLimit.MaxVisitors < 0 {
+		return fmt.Errorf("invalid rate_limit.max_visitors '%d': must be 0 (default) or more", c.Security.RateLimit.MaxVisitors)
+	}
+
+	// Login RateLimit Check
+	if c.Security.LoginRateLimit.Rate <= 0 || c.Security.LoginRateLimit.Burst < 1 {
+		return fmt.Errorf("security.login_rate_limit needs a rate above 0 and a burst of at least 1")
+	}
+	if d, err := time.ParseDuration(c.Security.LoginRateLimit.FailureDelay); err != nil || d < 0 {
+		return fmt.Errorf("invalid security.login_rate_limit.failure_delay '%s'", c.Security.LoginRateLimit.FailureDelay)
+	}
+
+	// Console UI: Page Size Check (the upper bound protects memory: every item is a DTO with keys)
+	if c.ConsoleUI.MaxPageSize < 1 || c.ConsoleUI.MaxPageSize > MaxConsolePageSize {
+		return fmt.Errorf("consoleui.max_page_size must be between 1 and %d", MaxConsolePageSize)
+	}
+
 	// Console UI Credentials Check
 	if c.ConsoleUI.Enabled {
 
@@ -144,3 +351,47 @@ func (c *Config) Validate() error {
 	}
 	return nil
 }
+
+// FontWeightValues maps ?weight names (and image.font_weights keys) to CSS numeric weights.
+var FontWeightValues = map[string]int{
+	"light":    300,
+	"regular":  400,
+	"medium":   500,
+	"semibold": 600,
+	"bold":     700,
+}
+
+// validFontName: ?font values are plain identifiers, so they are safe in URLs and cache keys.
+func validFontName(name string) bool {
+	if name == "" || len(name) > 32 {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// reservedResponseHeaders are set by handlers or other middlewares per response.
+// A static value from config would break content negotiation, caching or CORS.
+var reservedResponseHeaders = map[string]bool{
+	"Content-Type":      true,
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+	"Cache-Control":     true,
+	"Etag":              true,
+	"Last-Modified":     true,
+	"Vary":              true,
+	"Location":          true,
+	"Set-Cookie":        true,
+	"Retry-After":       true,
+}
+
+// IsReservedResponseHeader reports whether name may not be set through server.response_headers.
+func IsReservedResponseHeader(name string) bool {
+	name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+	return reservedResponseHeaders[name] || strings.HasPrefix(name, "Access-Control-")
+}
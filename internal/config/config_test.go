@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestDefaultImageSize(t *testing.T) {
+	// No config.yaml in the working directory: Load falls back to setDefaults
+	t.Chdir(t.TempDir())
+	t.Setenv("ADMIN_DASHBOARD_USERNAME", "admin")
+	t.Setenv("ADMIN_DASHBOARD_PASSWORD", "admin")
+
+	Load()
+
+	if got := AppConfig.Image.DefaultSize; got != 256 {
+		t.Fatalf("AppConfig.Image.DefaultSize = %d, want 256", got)
+	}
+}
+
+// The default must be set under the struct's mapstructure key, or Unmarshal ignores it.
+func TestSetDefaults(t *testing.T) {
+	v := viper.New()
+	setDefaults(v)
+
+	var c Config
+	if err := v.Unmarshal(&c); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if c.Image.DefaultSize != 256 {
+		t.Errorf("image.default_size = %d, want 256", c.Image.DefaultSize)
+	}
+	if c.Cache.EvictionPolicy != "lru" {
+		t.Errorf("cache.eviction_policy = %q, want \"lru\"", c.Cache.EvictionPolicy)
+	}
+}
+
+// Fields missing from the saved file keep the base value; a missing file is not an error.
+func TestLoadSettings(t *testing.T) {
+	base := Settings{Version: SettingsVersion}
+	base.Image.Quality = 80
+	base.Cache.TTL = "30m"
+
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if got, err := LoadSettings(path, base); err != nil || got.Image.Quality != 80 {
+		t.Fatalf("missing file: got quality %d, err %v; want 80, nil", got.Image.Quality, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"cache":{"ttl":"1h"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadSettings(path, base)
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if got.Cache.TTL != "1h" || got.Image.Quality != 80 {
+		t.Errorf("got ttl %q, quality %d; want 1h, 80", got.Cache.TTL, got.Image.Quality)
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SettingsVersion is bumped when the Settings layout changes incompatibly.
+const SettingsVersion = 1
+
+// Settings is the portable subset of the configuration that the console can export and
+// import: rate limits, cache, CORS and image defaults. Keys mirror config.yaml, so the
+// saved file is merged over config.yaml at startup (environment variables still win).
+// Secrets (upload secret, console credentials) and deployment details (paths, port,
+// cache backend and Redis URL) are never part of it.
+type Settings struct {
+	Version  int              `json:"version"`
+	Security SecuritySettings `json:"security"`
+	Cache    CacheSettings    `json:"cache"`
+	Image    ImageSettings    `json:"image"`
+}
+
+type SecuritySettings struct {
+	CorsOrigins     []string          `json:"cors_origins"`
+	PublicImageCors bool              `json:"public_image_cors"`
+	RateLimit       RateLimitSettings `json:"rate_limit"`
+}
+
+type RateLimitSettings struct {
+	Enabled     bool   `json:"enabled"`
+	Requests    int    `json:"requests"`
+	Window      string `json:"window"`
+	Burst       int    `json:"burst"`
+	MaxVisitors int    `json:"max_visitors"`
+}
+
+type CacheSettings struct {
+	Enabled        bool   `json:"enabled"`
+	MaxCapacity    int    `json:"max_capacity"`
+	TTL            string `json:"ttl"`
+	GeneratedTTL   string `json:"generated_ttl"`
+	EvictionPolicy string `json:"eviction_policy"`
+}
+
+type ImageSettings struct {
+	DefaultSize   int    `json:"default_size"`
+	Quality       int    `json:"quality"`
+	UploadFormat  string `json:"upload_format"`
+	AllowAVIF     bool   `json:"allow_avif"`
+	MaxUploadSize string `json:"max_upload_size"`
+	MaxKeyLimit   int    `json:"max_key_limit"`
+	ServeWebP     bool   `json:"serve_webp"`
+	SizeStep      int    `json:"size_step"`
+}
+
+// ExportSettings snapshots the current values.
+func (c *Config) ExportSettings() Settings {
+	return Settings{
+		Version: SettingsVersion,
+		Security: SecuritySettings{
+			CorsOrigins:     append([]string{}, c.Security.CorsOrigins...),
+			PublicImageCors: c.Security.PublicImageCors,
+			RateLimit: RateLimitSettings{
+				Enabled:     c.Security.RateLimit.Enabled,
+				Requests:    c.Security.RateLimit.Requests,
+				Window:      c.Security.RateLimit.Window,
+				Burst:       c.Security.RateLimit.Burst,
+				MaxVisitors: c.Security.RateLimit.MaxVisitors,
+			},
+		},
+		Cache: CacheSettings{
+			Enabled:        c.Cache.Enabled,
+			MaxCapacity:    c.Cache.MaxCapacity,
+			TTL:            c.Cache.TTL,
+			GeneratedTTL:   c.Cache.GeneratedTTL,
+			EvictionPolicy: c.Cache.EvictionPolicy,
+		},
+		Image: ImageSettings{
+			DefaultSize:   c.Image.DefaultSize,
+			Quality:       c.Image.Quality,
+			UploadFormat:  c.Image.UploadFormat,
+			AllowAVIF:     c.Image.AllowAVIF,
+			MaxUploadSize: c.Image.MaxUploadSize,
+			MaxKeyLimit:   c.Image.MaxKeyLimit,
+			ServeWebP:     c.Image.ServeWebP,
+			SizeStep:      c.Image.SizeStep,
+		},
+	}
+}
+
+// WithSettings returns a copy of c with s applied. It does not validate; call Validate on the result.
+func (c *Config) WithSettings(s Settings) Config {
+	out := *c
+	out.Security.CorsOrigins = append([]string{}, s.Security.CorsOrigins...)
+	out.Security.PublicImageCors = s.Security.PublicImageCors
+	out.Security.RateLimit = RateLimitConfig(s.Security.RateLimit)
+	out.Cache.Enabled = s.Cache.Enabled
+	out.Cache.MaxCapacity = s.Cache.MaxCapacity
+	out.Cache.TTL = s.Cache.TTL
+	out.Cache.GeneratedTTL = s.Cache.GeneratedTTL
+	out.Cache.EvictionPolicy = s.Cache.EvictionPolicy
+	out.Image.DefaultSize = s.Image.DefaultSize
+	out.Image.Quality = s.Image.Quality
+	out.Image.UploadFormat = s.Image.UploadFormat
+	out.Image.AllowAVIF = s.Image.AllowAVIF
+	out.Image.MaxUploadSize = s.Image.MaxUploadSize
+	out.Image.MaxKeyLimit = s.Image.MaxKeyLimit
+	out.Image.ServeWebP = s.Image.ServeWebP
+	out.Image.SizeStep = s.Image.SizeStep
+	return out
+}
+
+// LoadSettings reads the settings saved at path (consoleui.settings_file) over base, so fields
+// missing from the file keep base's value. A missing file returns base unchanged.
+func LoadSettings(path string, base Settings) (Settings, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return base, nil
+	}
+	if err != nil {
+		return base, fmt.Errorf("read settings: %w", err)
+	}
+	s := base
+	if err := json.Unmarshal(data, &s); err != nil {
+		return base, fmt.Errorf("parse settings: %w", err)
+	}
+	return s, nil
+}
+
+// SaveSettings writes s to path (consoleui.settings_file) atomically: a crash mid-write
+// must not leave a truncated file that breaks the next startup.
+func SaveSettings(path string, s Settings) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create settings directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write settings: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replace settings: %w", err)
+	}
+	return nil
+}
@@ -46,6 +46,9 @@ type ServerConfig struct {
 
 	// Env: Execution context (development, staging, production)
 	Env string `mapstructure:"env"`
+
+	// ResponseHeaders: Extra headers added to every response (e.g., X-Frame-Options: DENY)
+	ResponseHeaders map[string]string `mapstructure:"response_headers"`
 }
 
 type DatabaseConfig struct {
@@ -57,6 +60,21 @@ type DatabaseConfig struct {
 
 	// PruneInterval: Frequency of background cleanup tasks (e.g., "5m", "1h")
 	PruneInterval string `mapstructure:"prune_interval"`
+
+	// MaxConcurrentWrites: Write transactions allowed in flight; the rest queue in memory (e.g., 10)
+	MaxConcurrentWrites int `mapstructure:"max_concurrent_writes"`
+
+	// WriteQueueTimeout: How long a write waits for a free slot before answering 503 (e.g., "5s")
+	WriteQueueTimeout string `mapstructure:"write_queue_timeout"`
+
+	// ReadPoolSize: Read-only connections next to the single writer (e.g., 4; 0 = reads share the writer)
+	ReadPoolSize int `mapstructure:"read_pool_size"`
+
+	// PrepareStmt: Caches prepared statements per connection (faster repeated queries)
+	PrepareStmt bool `mapstructure:"prepare_stmt"`
+
+	// PrepareStmtMaxSize: LRU bound of that cache per pool (e.g., 256)
+	PrepareStmtMaxSize int `mapstructure:"prepare_stmt_max_size"`
 }
 
 type ImageConfig struct {
@@ -66,12 +84,100 @@ type ImageConfig struct {
 	// Quality: Compression level for image output (1-100)
 	Quality int `mapstructure:"quality"`
 
+	// FontPath: TrueType/OpenType font used for initials and text watermarks (e.g., "fonts/Inter_28pt-SemiBold.ttf")
+	FontPath string `mapstructure:"font_path"`
+
+	// Fonts: Extra fonts selectable per request with ?font=<name> (e.g., [{name: "serif", path: "fonts/Lora.ttf"}])
+	Fonts []FontConfig `mapstructure:"fonts"`
+
+	// FontWeight: CSS weight of font_path, used for ?weight matching and SVG font-weight (e.g., 600)
+	FontWeight int `mapstructure:"font_weight"`
+
+	// FontWeights: Other weights of the default font for ?weight=<name> (e.g., {bold: "fonts/Inter-Bold.ttf"})
+	FontWeights map[string]string `mapstructure:"font_weights"`
+
+
+	// UploadFormat: Encoding of resized uploads when the request has no 'format' field (jpeg, png, webp)
+	UploadFormat string `mapstructure:"upload_format"`
+
+	// AllowAVIF: Enables format=avif for generated avatars (CPU-heavy encoder, off by default)
+	AllowAVIF bool `mapstructure:"allow_avif"`
 
 	// MaxUploadSize: Maximum payload size for the /upload endpoint (e.g., "5MB")
 	MaxUploadSize string `mapstructure:"max_upload_size"`
 
 	// MaxKeyLimit: Maximum number of aliases allowed for a single asset mapping (e.g., 7)
 	MaxKeyLimit int `mapstructure:"max_key_limit"`
+
+	// ServeWebP: Transcode stored JPEG/PNG uploads to WebP for clients sending "Accept: image/webp" (costs CPU, cached per asset)
+	ServeWebP bool `mapstructure:"serve_webp"`
+
+	// SizeStep: Rounds requested avatar sizes to the nearest multiple (e.g., 16). 0 disables bucketing
+	SizeStep int `mapstructure:"size_step"`
+
+	// MaxConcurrentGeneration: Upper bound of parallel avatar renders (0 = 2x CPU cores)
+	MaxConcurrentGeneration int `mapstructure:"max_concurrent_generation"`
+
+	// GenerationTimeout: Max wait for a free render slot before responding 503 (e.g., "3s")
+	GenerationTimeout string `mapstructure:"generation_timeout"`
+
+	// CoalesceTimeout: Max wait on a shared in-flight render or GitHub fetch before responding 504 (e.g., "10s")
+	CoalesceTimeout string `mapstructure:"coalesce_timeout"`
+
+	// GitHubMaxConcurrent: Upper bound of parallel outbound GitHub fetches across all usernames (e.g., 8)
+	GitHubMaxConcurrent int `mapstructure:"github_max_concurrent"`
+
+	// GitHubQueueTimeout: Max wait for a free GitHub slot before serving the generated fallback (e.g., "2s")
+	GitHubQueueTimeout string `mapstructure:"github_queue_timeout"`
+
+	// DegradeUnderLoad: Serve a small, uncached avatar instead of 503 when render slots are saturated
+	DegradeUnderLoad bool `mapstructure:"degrade_under_load"`
+
+	// DegradeThreshold: Fraction of busy render slots (0-1) that triggers degraded output (e.g., 0.8)
+	DegradeThreshold float64 `mapstructure:"degrade_threshold"`
+
+	// Moderation: Optional content check run on uploads before they are stored
+	Moderation ModerationConfig `mapstructure:"moderation"`
+
+	// Watermark: Optional logo/text overlay for processed (non-original) uploads
+	Watermark WatermarkConfig `mapstructure:"watermark"`
+}
+
+type WatermarkConfig struct {
+	// Enabled: Apply the watermark by default (uploads can override with the 'watermark' form field)
+	Enabled bool `mapstructure:"enabled"`
+
+	// Image: Path to a logo file, preferably a transparent PNG (takes precedence over Text)
+	Image string `mapstructure:"image"`
+
+	// Text: Text rendered as watermark when no Image is set (e.g., "© Octa")
+	Text string `mapstructure:"text"`
+
+	// Position: top-left, top-right, bottom-left, bottom-right or center
+	Position string `mapstructure:"position"`
+
+	// Opacity: Watermark opacity between 0 and 1 (e.g., 0.5)
+	Opacity float64 `mapstructure:"opacity"`
+
+	// Scale: Watermark width relative to the image width (e.g., 0.2)
+	Scale float64 `mapstructure:"scale"`
+}
+
+type ModerationConfig struct {
+	// Enabled: Global toggle for upload moderation
+	Enabled bool `mapstructure:"enabled"`
+
+	// Endpoint: URL receiving a JPEG copy of each upload and answering {"allowed": bool, "reason": "..."}
+	Endpoint string `mapstructure:"endpoint"`
+
+	// APIKey: Optional bearer token sent to the moderation endpoint
+	APIKey string `mapstructure:"api_key"`
+
+	// Timeout: Max duration of a single moderation call (e.g., "5s")
+	Timeout string `mapstructure:"timeout"`
+
+	// FailOpen: Accept uploads when the moderator is unreachable (false = reject with 503)
+	FailOpen bool `mapstructure:"fail_open"`
 }
 
 type CacheConfig struct {
@@ -83,6 +189,21 @@ type CacheConfig struct {
 
 	// TTL: Expiration time for cached items (e.g., "30m", "24h")
 	TTL string `mapstructure:"ttl"`
+
+	// GeneratedTTL: Expiration time for generated avatars and montages, which only change with the URL (e.g., "24h")
+	GeneratedTTL string `mapstructure:"generated_ttl"`
+
+	// EvictionPolicy: Which items are dropped first when the cache is full (ttl, lru, lfu, fifo)
+	EvictionPolicy string `mapstructure:"eviction_policy"`
+
+	// Backend: Where cached items live: "memory" (per instance) or "redis" (shared by all instances)
+	Backend string `mapstructure:"backend"`
+
+	// RedisURL: Connection URL for backend "redis" (e.g., "redis://:password@localhost:6379/0")
+	RedisURL string `mapstructure:"redis_url"`
+
+	// PreloadCount: Most recently updated assets loaded into the cache at startup (0 = disabled)
+	PreloadCount int `mapstructure:"preload_count"`
 }
 
 type SecurityConfig struct {
@@ -92,8 +213,14 @@ type SecurityConfig struct {
 	// CorsOrigins: List of allowed domains for browser-based cross-origin requests
 	CorsOrigins []string `mapstructure:"cors_origins"`
 
+	// PublicImageCors: Serve image routes (/avatar, /u) with "Access-Control-Allow-Origin: *" and no credentials
+	PublicImageCors bool `mapstructure:"public_image_cors"`
+
 	// RateLimit: DDoS protection logic using a token-bucket algorithm
 	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+
+	// LoginRateLimit: Brute-force protection of the console login, per client IP
+	LoginRateLimit LoginRateLimitConfig `mapstructure:"login_rate_limit"`
 }
 
 type RateLimitConfig struct {
@@ -108,6 +235,20 @@ type RateLimitConfig struct {
 
 	// Burst: Temporary allowed spike capacity above the steady-rate limit
 	Burst int `mapstructure:"burst"`
+
+	// MaxVisitors: Most client IPs tracked at once; the least recently seen is dropped beyond it (e.g., 100000, 0 = default)
+	MaxVisitors int `mapstructure:"max_visitors"`
+}
+
+type LoginRateLimitConfig struct {
+	// Rate: Login attempts refilled per second (e.g., 1, or 0.2 for one every 5 seconds)
+	Rate float64 `mapstructure:"rate"`
+
+	// Burst: Attempts allowed back to back before the rate applies
+	Burst int `mapstructure:"burst"`
+
+	// FailureDelay: Pause before answering a failed login, slowing down scripts (e.g., "500ms")
+	FailureDelay string `mapstructure:"failure_delay"`
 }
 
 type ConsoleUIConfig struct {
@@ -121,4 +262,18 @@ type ConsoleUIConfig struct {
 		// Password: Admin login secret
 		Password string `mapstructure:"password"`
 	} `mapstructure:"user"`
-}
\ No newline at end of file
+
+	// MaxPageSize: Largest ?limit accepted by the asset list; larger values are clamped (e.g., 100, at most 1000)
+	MaxPageSize int `mapstructure:"max_page_size"`
+
+	// SettingsFile: Where settings imported from the console are saved and read back at startup (e.g., "data/settings.json")
+	SettingsFile string `mapstructure:"settings_file"`
+}
+
+// FontConfig is one entry of image.fonts.
+type FontConfig struct {
+	// Name: Value of ?font, lowercase letters, digits, '-' and '_' (e.g., "serif")
+	Name string `mapstructure:"name"`
+	// Path: TrueType/OpenType file (e.g., "fonts/Lora-SemiBold.ttf")
+	Path string `mapstructure:"path"`
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"os"
 	"time"
 
@@ -63,23 +64,32 @@ func StartCleaner() {
 	}
 }
 
-// checkAndPrune analyzes the database size and performs Vacuum or Prune operations.
-func checkAndPrune(limitBytes int64) {
+// FileSizes returns the on-disk size of the database file and of its WAL (0 when there is none).
+func FileSizes() (fileSize, walSize int64, err error) {
 	dbPath := config.AppConfig.Database.Path
 
-	// 1. Check Physical Size (Disk Usage)
 	fileInfo, err := os.Stat(dbPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if walInfo, err := os.Stat(dbPath + "-wal"); err == nil {
+		walSize = walInfo.Size()
+	}
+	return fileInfo.Size(), walSize, nil
+}
+
+// checkAndPrune analyzes the database size and performs Vacuum or Prune operations.
+func checkAndPrune(limitBytes int64) {
+	// 1. Check Physical Size (Disk Usage)
+	fileSize, walSize, err := FileSizes()
 	if err != nil {
 	
 		logger.LogError("Cleaner failed to stat DB file: %v", err)
 		return
 	}
 
-	physicalSize := fileInfo.Size()
 	// Include WAL file in size calculation as it consumes disk space
-	if walInfo, err := os.Stat(dbPath + "-wal"); err == nil {
-		physicalSize += walInfo.Size()
-	}
+	physicalSize := fileSize + walSize
 
 	// Performance Optimization:
 	// If below limit, do nothing. We keep the allocated space for future writes.
@@ -111,14 +121,18 @@ func checkAndPrune(limitBytes int64) {
 	if isBloated {
 	
 
-		logger.LogWarn("DB is bloated (>50% empty). Starting VACUUM to reclaim space...")
+		// A backup or a manual VACUUM is running; the next tick checks again.
+		if !MaintenanceMu.TryLock() {
+			logger.LogWarn("DB is bloated, but another maintenance operation is running. VACUUM postponed.")
+			return
+		}
+		defer MaintenanceMu.Unlock()
 
-		// Safety: Commit WAL to main DB before vacuuming to prevent data loss risk
-		DB.Exec("PRAGMA wal_checkpoint(TRUNCATE);")
+		logger.LogWarn("DB is bloated (>50%% empty). Starting VACUUM to reclaim space...")
 
-		// Vacuum rebuilds the DB file. This is blocking but necessary here.
+		// Vacuum rebuilds the DB file (after a WAL checkpoint). This is blocking but necessary here.
 		startTime := time.Now()
-		if err := DB.Exec("VACUUM;").Error; err != nil {
+		if err := Vacuum(context.Background()); err != nil {
 			
 					logger.LogError("VACUUM failed: %v", err)
 		} else {
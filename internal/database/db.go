@@ -18,6 +18,11 @@ import (
 
 var DB *gorm.DB
 
+// ReadDB serves read-only queries (avatar lookups, console listings) from its own small pool,
+// so they run next to the single writer instead of queueing behind it. WAL allows this.
+// It is DB itself when database.read_pool_size is 0.
+var ReadDB *gorm.DB
+
 // InitDB initializes the SQLite connection with performance-tuned settings (WAL mode).
 // It handles directory creation, connection pooling configuration, schema migrations,
 // and pre-loading of statistical data.
@@ -37,14 +42,8 @@ func InitDB() {
 		dbPath,
 	)
 
-	gormConfig := &gorm.Config{
-		Logger:                 gormLogger.Default.LogMode(gormLogger.Silent),
-		PrepareStmt:            true,
-		SkipDefaultTransaction: true, // Improves write performance by ~30%
-	}
-
 	var err error
-	DB, err = gorm.Open(sqlite.Open(dsn), gormConfig)
+	DB, err = gorm.Open(sqlite.Open(dsn), newGormConfig())
 	if err != nil {
 		log.Fatalf("[FATAL] Database connection failed: %v", err)
 	}
@@ -53,9 +52,33 @@ func InitDB() {
 	runMigrations(DB)
 	loadInitialStats(DB)
 
+	// Readers open after migrations so the file exists and is already in WAL mode.
+	ReadDB = DB
+	if n := config.AppConfig.Database.ReadPoolSize; n > 0 {
+		readDSN := fmt.Sprintf("%s?_query_only=true&_busy_timeout=5000&_cache_size=-20000", dbPath)
+		readDB, err := gorm.Open(sqlite.Open(readDSN), newGormConfig())
+		if err != nil {
+			log.Fatalf("[FATAL] Read-only database connection failed: %v", err)
+		}
+		configureReadPool(readDB, n)
+		ReadDB = readDB
+	}
+
 		logger.LogInfo("Database initialized successfully")
 }
 
+// newGormConfig returns a fresh config per pool: gorm.Open keeps and mutates the one it is given.
+// Prepared statements are cached per SQL string. Queries whose SQL varies with input
+// ("IN ?" lists, tag filters) would grow the cache forever, so it is a bounded LRU.
+func newGormConfig() *gorm.Config {
+	return &gorm.Config{
+		Logger:                 gormLogger.Default.LogMode(gormLogger.Silent),
+		PrepareStmt:            config.AppConfig.Database.PrepareStmt,
+		PrepareStmtMaxSize:     config.AppConfig.Database.PrepareStmtMaxSize,
+		SkipDefaultTransaction: true, // Improves write performance by ~30%
+	}
+}
+
 func ensureDir(path string) error {
 	dir := filepath.Dir(path)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
@@ -76,6 +99,18 @@ func configurePool(db *gorm.DB) {
 	sqlDB.SetConnMaxLifetime(1 * time.Hour)
 }
 
+// configureReadPool sizes the read-only pool. query_only makes any stray write fail loudly.
+func configureReadPool(db *gorm.DB, size int) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("[FATAL] Failed to retrieve generic database interface: %v", err)
+	}
+
+	sqlDB.SetMaxOpenConns(size)
+	sqlDB.SetMaxIdleConns(size)
+	sqlDB.SetConnMaxLifetime(1 * time.Hour)
+}
+
 func runMigrations(db *gorm.DB) {
 	if err := db.AutoMigrate(&Image{}, &KeyMapping{}); err != nil {
 		log.Fatalf("[FATAL] Schema migration failed: %v", err)
@@ -85,6 +120,8 @@ func runMigrations(db *gorm.DB) {
 	indices := []string{
 		"CREATE INDEX IF NOT EXISTS idx_images_updated_at ON images(updated_at DESC);",
 		"CREATE INDEX IF NOT EXISTS idx_key_mappings_image_id ON key_mappings(image_id);",
+		// Covering index for key searches: DISTINCT image_id over a key range never touches the table.
+		"CREATE INDEX IF NOT EXISTS idx_key_mappings_key_image_id ON key_mappings(key, image_id);",
 	}
 
 	for _, idx := range indices {
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Key lookups and searches must stay on the key_mappings primary key index; at tens of
+// thousands of keys a full scan turns a millisecond query into seconds.
+//
+// SQLite's LIKE is case-insensitive, so it cannot use the (binary) primary key index by itself.
+// Keys are stored lowercase, which makes an explicit [lo, hi) range on the literal prefix exact.
+
+// WhereKeyPrefix narrows q to keys starting with the literal prefix ('%' and '_' are not wildcards).
+func WhereKeyPrefix(q *gorm.DB, prefix string) *gorm.DB {
+	lo, hi := keyPrefixRange(strings.ToLower(prefix))
+	if lo != "" {
+		q = q.Where("key >= ?", lo)
+	}
+	if hi != "" {
+		q = q.Where("key < ?", hi)
+	}
+	return q
+}
+
+// WhereKeyLike applies a LIKE pattern (e.g. "nature/mo%"). The literal part before the first
+// wildcard becomes an indexed range; the LIKE itself keeps the pattern semantics.
+func WhereKeyLike(q *gorm.DB, pattern string) *gorm.DB {
+	literal := pattern
+	if i := strings.IndexAny(pattern, "%_"); i >= 0 {
+		literal = pattern[:i]
+	}
+	return WhereKeyPrefix(q, literal).Where("key LIKE ?", pattern)
+}
+
+// keyPrefixRange returns bounds so that lo <= key < hi holds exactly for keys with the prefix.
+// hi is "" when there is no upper bound (empty prefix or a prefix of 0xff bytes).
+func keyPrefixRange(prefix string) (lo, hi string) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return prefix, string(b[:i+1])
+		}
+	}
+	return prefix, ""
+}
+
+// UnindexedKeyQueries runs EXPLAIN QUERY PLAN on the hot key_mappings queries (avatar lookup,
+// console search and its distinct count) and describes every one that scans the table.
+// An empty result means all of them use an index.
+func UnindexedKeyQueries(db *gorm.DB) ([]string, error) {
+	queries := map[string]func(tx *gorm.DB) *gorm.DB{
+		"key lookup": func(tx *gorm.DB) *gorm.DB {
+			var m KeyMapping
+			return tx.Select("image_id").First(&m, "key = ?", "probe")
+		},
+		"key search": func(tx *gorm.DB) *gorm.DB {
+			var ids []string
+			return WhereKeyLike(tx.Table("key_mappings"), "probe%").Select("DISTINCT image_id").Limit(50).Pluck("image_id", &ids)
+		},
+		"key search count": func(tx *gorm.DB) *gorm.DB {
+			var n int64
+			return WhereKeyLike(tx.Table("key_mappings"), "probe%").Distinct("image_id").Count(&n)
+		},
+	}
+
+	var problems []string
+	for _, name := range []string{"key lookup", "key search", "key search count"} { // Stable order
+		sql := db.ToSQL(queries[name])
+
+		var plan []struct{ Detail string }
+		if err := db.Raw("EXPLAIN QUERY PLAN " + sql).Scan(&plan).Error; err != nil {
+			return nil, err
+		}
+		for _, step := range plan {
+			// "SCAN key_mappings" (optionally "USING COVERING INDEX") walks every row; "SEARCH" seeks.
+			if strings.HasPrefix(step.Detail, "SCAN key_mappings") {
+				problems = append(problems, name+": "+step.Detail)
+			}
+		}
+	}
+	return problems, nil
+}
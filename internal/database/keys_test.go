@@ -0,0 +1,89 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// openTestDB opens a throwaway database file; migrate applies the real schema and indexes.
+func openTestDB(t *testing.T, migrate bool) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: gormLogger.Default.LogMode(gormLogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if migrate {
+		runMigrations(db)
+	}
+	return db
+}
+
+func TestKeyQueriesUseIndexes(t *testing.T) {
+	db := openTestDB(t, true)
+
+	// Enough rows (and fresh statistics) that the planner has a real choice to make
+	mappings := make([]KeyMapping, 0, 2000)
+	for i := 0; i < cap(mappings); i++ {
+		mappings = append(mappings, KeyMapping{Key: fmt.Sprintf("folder-%d/user-%d", i%20, i), ImageID: fmt.Sprintf("img-%d", i%500)})
+	}
+	if err := db.CreateInBatches(mappings, 500).Error; err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if err := db.Exec("ANALYZE;").Error; err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+
+	problems, err := UnindexedKeyQueries(db)
+	if err != nil {
+		t.Fatalf("UnindexedKeyQueries: %v", err)
+	}
+	for _, p := range problems {
+		t.Errorf("full table scan: %s", p)
+	}
+}
+
+// Without the primary key and indexes every query scans, so the check must report all of them.
+func TestUnindexedKeyQueriesReportsScans(t *testing.T) {
+	db := openTestDB(t, false)
+	if err := db.Exec("CREATE TABLE key_mappings (key TEXT, image_id TEXT, created_at DATETIME);").Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	problems, err := UnindexedKeyQueries(db)
+	if err != nil {
+		t.Fatalf("UnindexedKeyQueries: %v", err)
+	}
+	if len(problems) != 3 {
+		t.Errorf("got %d problems, want 3 (one per query): %q", len(problems), problems)
+	}
+}
+
+func TestKeyPrefixRange(t *testing.T) {
+	tests := []struct {
+		prefix, lo, hi string
+	}{
+		{"", "", ""},
+		{"nature/", "nature/", "nature0"},
+		{"ab", "ab", "ac"},
+		{"a\xff", "a\xff", "b"},
+		{"\xff\xff", "\xff\xff", ""},
+	}
+	for _, tt := range tests {
+		lo, hi := keyPrefixRange(tt.prefix)
+		if lo != tt.lo || hi != tt.hi {
+			t.Errorf("keyPrefixRange(%q) = (%q, %q), want (%q, %q)", tt.prefix, lo, hi, tt.lo, tt.hi)
+		}
+	}
+}
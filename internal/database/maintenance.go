@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// MaintenanceMu serializes heavy whole-database operations: backup snapshots, VACUUM and
+// WAL checkpoints. Callers use TryLock so a second request fails fast instead of queueing
+// behind a job that can take minutes.
+var MaintenanceMu sync.Mutex
+
+// CheckpointResult is the row returned by PRAGMA wal_checkpoint. After a complete TRUNCATE
+// checkpoint the WAL is empty, so both frame counts are 0; they are only set when Busy.
+type CheckpointResult struct {
+	Busy               bool `json:"busy"`                // A reader or writer kept the checkpoint from finishing
+	LogFrames          int  `json:"log_frames"`          // Frames left in the WAL
+	CheckpointedFrames int  `json:"checkpointed_frames"` // Of those, frames already copied into the database file
+}
+
+// Checkpoint copies the WAL into the database file and truncates it (PRAGMA wal_checkpoint(TRUNCATE)).
+// The caller should hold MaintenanceMu.
+func Checkpoint(ctx context.Context) (CheckpointResult, error) {
+	var res CheckpointResult
+	if DB == nil {
+		return res, errors.New("database not initialized")
+	}
+
+	var busy int
+	row := DB.WithContext(ctx).Raw("PRAGMA wal_checkpoint(TRUNCATE);").Row()
+	if err := row.Scan(&busy, &res.LogFrames, &res.CheckpointedFrames); err != nil {
+		return res, err
+	}
+	res.Busy = busy != 0
+	return res, nil
+}
+
+// Vacuum checkpoints the WAL, then rebuilds the database file to give free pages back to the disk.
+// VACUUM blocks writers until it finishes. The caller should hold MaintenanceMu.
+func Vacuum(ctx context.Context) error {
+	// Safety: Commit WAL to main DB before vacuuming to prevent data loss risk
+	if _, err := Checkpoint(ctx); err != nil {
+		return err
+	}
+	if err := DB.WithContext(ctx).Exec("VACUUM;").Error; err != nil {
+		return err
+	}
+
+	// In WAL mode the rebuilt pages land in the WAL first; fold them in so the file shrinks now.
+	_, err := Checkpoint(ctx)
+	return err
+}
@@ -13,6 +13,8 @@ type Image struct {
 	Format string `json:"format"` // "jpeg", "png", "webp"
 	Size   int64  `json:"size"`
 
+	Metadata string `gorm:"type:text" json:"-"` // Optional tags as JSON text: {"owner":"x","category":"logos"}
+
 	Mappings  []KeyMapping `gorm:"foreignKey:ImageID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	UpdatedAt time.Time    `gorm:"autoUpdateTime"`
 	CreatedAt time.Time    `json:"created_at"`
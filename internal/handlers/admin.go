@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,9 +11,12 @@ import (
 	"strings"
 	"time"
 
+	"gorm.io/gorm"
+
 	"octa/internal/appinfo"
 	"octa/internal/config"
 	"octa/internal/database"
+	"octa/pkg/logger"
 	"octa/pkg/utils"
 )
 
@@ -26,6 +30,8 @@ type AssetDTO struct {
 	URL       string `json:"url"`
 	Width     int    `json:"width"`
 	Height    int    `json:"height"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type ExtendedStatsDTO struct {
@@ -37,6 +43,9 @@ type ExtendedStatsDTO struct {
 	NumGoroutines int        `json:"num_goroutines"`
 	RecentUploads []AssetDTO `json:"recent_uploads"`
 	MaxUploadSize string     `json:"max_upload_size"`
+	CacheHits     uint64     `json:"cache_hits"`
+	CacheMisses   uint64     `json:"cache_misses"`
+	CacheItems    int        `json:"cache_items"`
 }
 
 type PaginatedResponse struct {
@@ -73,75 +82,21 @@ func GetStats(w http.ResponseWriter, r *http.Request) {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 
-	type RawResult struct {
-		ID        string
-		UpdatedAt time.Time
-		Size      int64
-		Width     int
-		Height    int
-		Keys      string
-	}
-	var recentImages []RawResult
+	var recentImages []assetRow
 	// database.DB.WithContext(r.Context()).Raw(queryAssets + " LIMIT 5").Scan(&results)
 
-	err := database.DB.WithContext(ctx).
+	err := database.ReadDB.WithContext(ctx).
 		Table("images").
-		Select("id, updated_at, size, width, height").
+		Select("id, updated_at, created_at, size, width, height, metadata").
 		Order("updated_at DESC").
 		Limit(5).
 		Scan(&recentImages).Error
 
 	if err != nil {
-		recentImages = []RawResult{}
+		recentImages = []assetRow{}
 	}
 
-	// baseURL := getBaseURL(r)
-	recentAssets := make([]AssetDTO, 0, len(recentImages))
-
-	if len(recentImages) > 0 {
-		imageIDs := make([]string, len(recentImages))
-		for i, img := range recentImages {
-			imageIDs[i] = img.ID
-		}
-
-		type KeyResult struct {
-			ImageID string
-			Key     string
-		}
-		var keys []KeyResult
-
-		database.DB.WithContext(ctx).
-			Table("key_mappings").
-			Select("image_id, key").
-			Where("image_id IN ?", imageIDs).
-			Scan(&keys)
-
-		keysMap := make(map[string][]string)
-		for _, k := range keys {
-			keysMap[k.ImageID] = append(keysMap[k.ImageID], k.Key)
-		}
-
-		baseURL := getBaseURL(r)
-		for _, img := range recentImages {
-			imgKeys := keysMap[img.ID]
-			keysStr := strings.Join(imgKeys, ", ")
-
-			urlKey := img.ID
-			if len(imgKeys) > 0 {
-				urlKey = imgKeys[0]
-			}
-
-			recentAssets = append(recentAssets, AssetDTO{
-				ID:        img.ID,
-				Keys:      keysStr,
-				Size:      img.Size,
-				Width:     img.Width,
-				Height:    img.Height,
-				CreatedAt: img.UpdatedAt.Format("2006-01-02 15:04"),
-				URL:       fmt.Sprintf("%s/u/%s", baseURL, strings.TrimSpace(urlKey)),
-			})
-		}
-	}
+	recentAssets := buildAssetDTOs(ctx, recentImages, getBaseURL(r))
 
 	stats := ExtendedStatsDTO{
 		TotalCount:    count,
@@ -153,10 +108,29 @@ func GetStats(w http.ResponseWriter, r *http.Request) {
 		RecentUploads: recentAssets,
 		MaxUploadSize: config.AppConfig.Image.MaxUploadSize,
 	}
+	if globalCache != nil {
+		cs := globalCache.Stats()
+		stats.CacheHits, stats.CacheMisses, stats.CacheItems = cs.Hits, cs.Misses, cs.Count
+	}
 
 	utils.WriteJSON(w, http.StatusOK, stats)
 }
 
+// GetTraffic returns live request stats (rps, error rate, p50/p95) over a sliding window.
+// Optional ?window=60 in seconds (max 300).
+// GET /console/api/traffic
+func GetTraffic(w http.ResponseWriter, r *http.Request) {
+	window := utils.ParseInt(r.URL.Query().Get("window"), 60, 1, appinfo.TrafficWindow)
+	utils.WriteJSON(w, http.StatusOK, appinfo.Traffic(window))
+}
+
+// DefaultPageSize is the asset list page size without ?limit; DefaultMaxPageSize is the
+// consoleui.max_page_size fallback.
+const (
+	DefaultPageSize    = 50
+	DefaultMaxPageSize = 100
+)
+
 // ListAssets returns a paginated list of all stored assets without binary data.
 // GET /console/api/assets
 func ListAssets(w http.ResponseWriter, r *http.Request) {
@@ -165,35 +139,46 @@ func ListAssets(w http.ResponseWriter, r *http.Request) {
 	pageStr := r.URL.Query().Get("page")
 	limitStr := r.URL.Query().Get("limit")
 	searchQuery := strings.TrimSpace(r.URL.Query().Get("q"))
+	tags := tagFilters(r.URL.Query()) // ?tag.category=logos
+
+	// Folder scope (?folder=nature/): a key-prefix search, so q searches inside the folder.
+	if folder := normalizeFolder(r.URL.Query().Get("folder")); folder != "" {
+		searchQuery = folder + strings.TrimPrefix(searchQuery, folder)
+	}
 
 	page, _ := strconv.Atoi(pageStr)
 	if page < 1 {
 		page = 1
 	}
 
+	// Page size: over consoleui.max_page_size is clamped (and flagged), not silently reset
+	maxLimit := config.AppConfig.ConsoleUI.MaxPageSize
+	if maxLimit < 1 {
+		maxLimit = DefaultMaxPageSize
+	}
 	limit, _ := strconv.Atoi(limitStr)
-	if limit < 1 || limit > 100 {
-		limit = 50
+	if limit < 1 {
+		limit = min(DefaultPageSize, maxLimit)
+	} else if limit > maxLimit {
+		limit = maxLimit
+		w.Header().Set("X-Limit-Clamped", "true")
 	}
 
 	offset := (page - 1) * limit
 
-	var results []struct {
-		ID        string
-		UpdatedAt time.Time
-		CreatedAt time.Time
-		Size      int64
-		Width     int
-		Height    int
-	}
+	var results []assetRow
 	var totalItems int64
 
 	if searchQuery == "" {
-		totalItems = appinfo.TotalAssetsCount.Load()
+		if len(tags) == 0 {
+			totalItems = appinfo.TotalAssetsCount.Load()
+		} else if err := applyTagFilters(database.ReadDB.WithContext(ctx).Table("images"), "metadata", tags).Count(&totalItems).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "DB Error")
+			return
+		}
 
-		err := database.DB.WithContext(ctx).
-			Table("images").
-			Select("id, updated_at, created_at, size, width, height").
+		err := applyTagFilters(database.ReadDB.WithContext(ctx).Table("images"), "metadata", tags).
+			Select("id, updated_at, created_at, size, width, height, metadata").
 			Order("updated_at DESC").
 			Limit(limit).
 			Offset(offset).
@@ -212,9 +197,17 @@ func ListAssets(w http.ResponseWriter, r *http.Request) {
 
 		likeStr = strings.TrimPrefix(likeStr, "%")
 
+		// Tag filters live on images, so join them in only when needed.
+		searchScope := func() *gorm.DB {
+			q := database.WhereKeyLike(database.ReadDB.WithContext(ctx).Table("key_mappings"), likeStr)
+			if len(tags) > 0 {
+				q = applyTagFilters(q.Joins("JOIN images ON images.id = key_mappings.image_id"), "images.metadata", tags)
+			}
+			return q
+		}
+
 		var imageIDs []string
-		err := database.DB.Table("key_mappings").
-			Where("key LIKE ?", likeStr).
+		err := searchScope().
 			Distinct("image_id").
 			Count(&totalItems).Error
 
@@ -225,9 +218,8 @@ func ListAssets(w http.ResponseWriter, r *http.Request) {
 
 		if totalItems > 0 {
 
-			err := database.DB.Table("key_mappings").
+			err := searchScope().
 				Select("DISTINCT image_id").
-				Where("key LIKE ?", likeStr).
 				Limit(limit).
 				Offset(offset).
 				Pluck("image_id", &imageIDs).Error
@@ -239,81 +231,109 @@ func ListAssets(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if len(imageIDs) > 0 {
-			database.DB.WithContext(ctx).
+			database.ReadDB.WithContext(ctx).
 				Table("images").
-				Select("id, updated_at, created_at, size, width, height").
+				Select("id, updated_at, created_at, size, width, height, metadata").
 				Where("id IN ?", imageIDs).
 				Scan(&results)
 		}
 	}
 
+	totalPages := int((totalItems + int64(limit) - 1) / int64(limit))
+	if totalPages < 0 {
+		totalPages = 0
+	}
+
+	setPaginationHeaders(w, r, page, limit, totalItems, totalPages)
+
 	if len(results) == 0 {
 		utils.WriteJSON(w, http.StatusOK, PaginatedResponse{
 			Items:      []AssetDTO{},
 			TotalItems: totalItems,
 			Page:       page,
 			Limit:      limit,
-			TotalPages: 0,
+			TotalPages: totalPages,
 		})
 		return
 	}
 
-	resultIDs := make([]string, len(results))
-	for i, r := range results {
-		resultIDs[i] = r.ID
+	assets := buildAssetDTOs(ctx, results, getBaseURL(r))
+
+	utils.WriteJSON(w, http.StatusOK, PaginatedResponse{
+		Items:      assets,
+		TotalItems: totalItems,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+	})
+}
+
+// assetRow is an images row without its data, as listed by the console.
+type assetRow struct {
+	ID        string
+	UpdatedAt time.Time
+	CreatedAt time.Time
+	Size      int64
+	Width     int
+	Height    int
+	Metadata  string
+}
+
+// assetTimeLayout is how the console shows asset timestamps.
+const assetTimeLayout = "2006-01-02 15:04"
+
+// buildAssetDTOs turns image rows into AssetDTOs with their keys, loading the keys of all rows in
+// one query (no N+1). The URL uses the first key, or the ID for assets without keys.
+func buildAssetDTOs(ctx context.Context, rows []assetRow, baseURL string) []AssetDTO {
+	assets := make([]AssetDTO, 0, len(rows))
+	if len(rows) == 0 {
+		return assets
 	}
 
-	type KeyRes struct {
+	imageIDs := make([]string, len(rows))
+	for i, row := range rows {
+		imageIDs[i] = row.ID
+	}
+
+	var keyRows []struct {
 		ImageID string
 		Key     string
 	}
-	var keyRows []KeyRes
-	database.DB.Table("key_mappings").
+	if err := database.ReadDB.WithContext(ctx).
+		Table("key_mappings").
 		Select("image_id, key").
-		Where("image_id IN ?", resultIDs).
-		Scan(&keyRows)
+		Where("image_id IN ?", imageIDs).
+		Order("created_at ASC").
+		Scan(&keyRows).Error; err != nil {
+		logger.LogWarn("Failed to load asset keys: %v", err)
+	}
 
-	keysMap := make(map[string][]string)
+	keysMap := make(map[string][]string, len(rows))
 	for _, k := range keyRows {
 		keysMap[k.ImageID] = append(keysMap[k.ImageID], k.Key)
 	}
 
-	baseURL := getBaseURL(r)
-	assets := make([]AssetDTO, 0, len(results))
+	for _, row := range rows {
+		imgKeys := keysMap[row.ID]
 
-	for _, res := range results {
-		imgKeys := keysMap[res.ID]
-		keysStr := strings.Join(imgKeys, ", ")
-
-		urlKey := res.ID
+		urlKey := row.ID
 		if len(imgKeys) > 0 {
 			urlKey = imgKeys[0]
 		}
 
 		assets = append(assets, AssetDTO{
-			ID:        res.ID,
-			Keys:      keysStr,
-			Size:      res.Size,
-			Width:     res.Width,
-			Height:    res.Height,
-			CreatedAt: res.CreatedAt.Format("2006-01-02 15:04"),
-			UpdatedAt: res.UpdatedAt.Format("2006-01-02 15:04"),
+			ID:        row.ID,
+			Keys:      strings.Join(imgKeys, ", "),
+			Size:      row.Size,
+			Width:     row.Width,
+			Height:    row.Height,
+			CreatedAt: row.CreatedAt.Format(assetTimeLayout),
+			UpdatedAt: row.UpdatedAt.Format(assetTimeLayout),
 			URL:       fmt.Sprintf("%s/u/%s", baseURL, strings.TrimSpace(urlKey)),
+			Metadata:  decodeMetadata(row.Metadata),
 		})
 	}
-
-	totalPages := int((totalItems + int64(limit) - 1) / int64(limit))
-	if totalPages < 0 {
-		totalPages = 0
-	}
-
-	utils.WriteJSON(w, http.StatusOK, PaginatedResponse{
-		Items:      assets,
-		TotalItems: totalItems,
-		Page:       page,
-		Limit:      limit,
-		TotalPages: totalPages,
-	})
+	return assets
 }
 
 // DELETE /console/api/assets/{id}
@@ -344,6 +364,9 @@ func DeleteAssetHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// MaxKeyLength: Longer keys are dropped when editing asset keys from the console.
+const MaxKeyLength = 30
+
 type UpdateKeysRequest struct {
 	Keys string `json:"keys"` // e.g., "new-key-1, new-key-2"
 }
@@ -365,61 +388,317 @@ func UpdateAssetKeys(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx := database.DB.WithContext(r.Context()).Begin()
+	newKeys, msg := cleanKeyList(strings.Split(req.Keys, ","))
+	if msg != "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrValidationInvalidFormat, msg)
+		return
+	}
+
+	tx, release, ok := beginAssetKeyEdit(w, r, id)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Rollback guard: Covers every early return (and panics) until Commit succeeds.
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	// Remember old keys: their cached lookups must go too, or removed keys keep resolving until TTL.
+	oldKeys, err := fetchAssetKeys(tx, id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to read asset keys.")
+		return
+	}
 
 	//  Clear existing keys
 	if err := tx.Where("image_id = ?", id).Delete(&database.KeyMapping{}).Error; err != nil {
-		tx.Rollback()
 		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to reset asset keys.")
 		return
 	}
 
 	// Insert new keys
-	newKeys := strings.Split(req.Keys, ",")
 	for _, k := range newKeys {
-		k = strings.TrimSpace(k)
-		k = utils.NormalizeKey(k)
-		k = strings.ToLower(k)
+		if err := tx.Create(&database.KeyMapping{Key: k, ImageID: id}).Error; err != nil {
+			// Likely a unique constraint violation
+			utils.WriteError(w, http.StatusConflict, utils.ErrResourceConflict, fmt.Sprintf("Key '%s' is already in use.", k))
+			return
+		}
+	}
+	if !checkAssetKeyLimit(w, tx, id) {
+		return
+	}
 
-		if k == "" || len(k) > 30 {
-			continue
+	// Read back the stored keys in their stored order (created_at), the same order listings use
+	finalKeys, err := fetchAssetKeys(tx, id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to read asset keys.")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Transaction failed.")
+		return
+	}
+	committed = true
+
+	invalidateKeyCache(append(oldKeys, newKeys...))
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"action":  "updated",
+		"message": "Asset keys updated successfully.",
+		"keys":    finalKeys,
+	})
+}
+
+type PatchKeysRequest struct {
+	Add    []string `json:"add"`    // e.g., ["team/alice"]
+	Remove []string `json:"remove"` // e.g., ["old-alias"]
+}
+
+// PatchAssetKeys adds/removes individual keys without touching the others.
+// PATCH /console/api/assets/{id}
+func PatchAssetKeys(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Asset ID is required.")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 2048)
+
+	var req PatchKeysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Invalid JSON body.")
+		return
+	}
+
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Provide at least one key in 'add' or 'remove'.")
+		return
+	}
+
+	// Validate everything before opening the transaction
+	toAdd, msg := cleanKeyList(req.Add)
+	if msg != "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrValidationInvalidFormat, msg)
+		return
+	}
+
+	toRemove := make([]string, 0, len(req.Remove))
+	for _, raw := range req.Remove {
+		if k, ok := cleanAssetKey(raw); ok {
+			toRemove = append(toRemove, k)
 		}
+	}
 
-		if !utils.IsValidKeyFormat(k) {
+	tx, release, ok := beginAssetKeyEdit(w, r, id)
+	if !ok {
+		return
+	}
+	defer release()
+
+	committed := false
+	defer func() {
+		if !committed {
 			tx.Rollback()
-			utils.WriteError(w, http.StatusBadRequest, utils.ErrValidationInvalidFormat,
-				fmt.Sprintf("Key '%s' contains invalid characters. Allowed: a-z, 0-9, -, _, /, @", k))
+		}
+	}()
+
+	if len(toRemove) > 0 {
+		if err := tx.Where("image_id = ? AND key IN ?", id, toRemove).Delete(&database.KeyMapping{}).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to remove asset keys.")
+			return
+		}
+	}
+
+	for _, k := range toAdd {
+		var existing database.KeyMapping
+		if err := tx.Where("key = ?", k).First(&existing).Error; err == nil {
+			if existing.ImageID == id {
+				continue // Already mapped, nothing to do
+			}
+			utils.WriteError(w, http.StatusConflict, utils.ErrResourceConflict, fmt.Sprintf("Key '%s' is already in use.", k))
 			return
 		}
 
 		if err := tx.Create(&database.KeyMapping{Key: k, ImageID: id}).Error; err != nil {
-			tx.Rollback()
-			// Likely a unique constraint violation
 			utils.WriteError(w, http.StatusConflict, utils.ErrResourceConflict, fmt.Sprintf("Key '%s' is already in use.", k))
 			return
 		}
 	}
+
+	if !checkAssetKeyLimit(w, tx, id) {
+		return
+	}
+
+	finalKeys, err := fetchAssetKeys(tx, id)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to read asset keys.")
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Transaction failed.")
 		return
 	}
+	committed = true
 
-	if globalCache != nil {
-		newKeysList := strings.Split(req.Keys, ",")
-		for _, k := range newKeysList {
-			k = utils.NormalizeKey(k)
-			if k != "" {
+	invalidateKeyCache(append(toAdd, toRemove...))
 
-				globalCache.Delete("map:" + k)
-			}
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"action":  "patched",
+		"message": "Asset keys patched successfully.",
+		"keys":    finalKeys,
+	})
+}
+
+// beginAssetKeyEdit is the shared start of PUT and PATCH on an asset's keys: it takes a DB write
+// slot (queued with uploads and bulk edits), opens a transaction and checks that the asset exists.
+// ok=false means the response is already written. Otherwise defer release, which frees the write
+// slot; rolling back an uncommitted tx stays with the caller.
+func beginAssetKeyEdit(w http.ResponseWriter, r *http.Request, id string) (tx *gorm.DB, release func(), ok bool) {
+	if err := acquireDBWrite(r.Context()); err != nil {
+		writeWriteBusy(w)
+		return nil, nil, false
+	}
+
+	tx = database.DB.WithContext(r.Context()).Begin()
+	if tx.Error != nil {
+		releaseDBWrite()
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to start transaction.")
+		return nil, nil, false
+	}
+
+	var exists int64
+	if err := tx.Model(&database.Image{}).Where("id = ?", id).Count(&exists).Error; err != nil || exists == 0 {
+		tx.Rollback()
+		releaseDBWrite()
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to read asset.")
+		} else {
+			utils.WriteError(w, http.StatusNotFound, utils.ErrResourceNotFound, "Asset not found.")
 		}
+		return nil, nil, false
 	}
 
-	utils.WriteJSON(w, http.StatusOK, map[string]string{
-		"status":  "success",
-		"action":  "updated",
-		"message": "Asset keys updated successfully.",
-	})
+	return tx, releaseDBWrite, true
+}
+
+// checkAssetKeyLimit enforces the upload alias ceiling (image.max_key_limit) on the keys the
+// asset has inside tx. false means the response is already written.
+func checkAssetKeyLimit(w http.ResponseWriter, tx *gorm.DB, id string) bool {
+	maxKeyLimit := config.AppConfig.Image.MaxKeyLimit
+	if maxKeyLimit == 0 {
+		maxKeyLimit = DefaultMaxKeyLimit
+	}
+
+	var keyCount int64
+	if err := tx.Model(&database.KeyMapping{}).Where("image_id = ?", id).Count(&keyCount).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to count asset keys.")
+		return false
+	}
+	if keyCount > int64(maxKeyLimit) {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid,
+			fmt.Sprintf("Too many keys: an asset can have at most %d.", maxKeyLimit))
+		return false
+	}
+	return true
+}
+
+// cleanAssetKey normalizes a raw key for storage.
+// ok=false means the key is empty or too long and should be skipped silently.
+func cleanAssetKey(raw string) (string, bool) {
+	k := strings.TrimSpace(raw)
+	k = utils.NormalizeKey(k)
+	k = strings.ToLower(k)
+
+	if k == "" || len(k) > MaxKeyLength {
+		return "", false
+	}
+	return k, true
+}
+
+// cleanKeyList normalizes keys the way UpdateAssetKeys stores them: empty or overlong entries
+// are dropped and duplicates collapse. Returns a client-facing message if a key has invalid characters.
+func cleanKeyList(raw []string) ([]string, string) {
+	keys := make([]string, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, r := range raw {
+		k, ok := cleanAssetKey(r)
+		if !ok || seen[k] {
+			continue
+		}
+		if !utils.IsValidKeyFormat(k) {
+			return nil, fmt.Sprintf("Key '%s' contains invalid characters. Allowed: a-z, 0-9, -, _, /, @", k)
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+	return keys, ""
+}
+
+// fetchAssetKeys returns the keys currently mapped to an asset, oldest first.
+func fetchAssetKeys(db *gorm.DB, id string) ([]string, error) {
+	keys := []string{}
+	err := db.Model(&database.KeyMapping{}).
+		Where("image_id = ?", id).
+		Order("created_at ASC").
+		Pluck("key", &keys).Error
+	return keys, err
+}
+
+// invalidateKeyCache drops cached key->image lookups so changes are visible immediately.
+// It also drops generated fallbacks cached for those keys (gen:<key>?...), which would
+// otherwise outlive a new mapping (upload, key edit or move) until TTL.
+func invalidateKeyCache(keys []string) {
+	if globalCache == nil || len(keys) == 0 {
+		return
+	}
+
+	genPrefixes := make([]string, 0, len(keys))
+	for _, k := range keys {
+		globalCache.Delete("map:" + k)
+		genPrefixes = append(genPrefixes, cacheKeyPrefix("gen", k))
+	}
+	globalCache.DeletePrefix(genPrefixes...)
+}
+
+// setPaginationHeaders mirrors the pagination body fields as headers for generic clients:
+// X-Total-Count, X-Page, X-Total-Pages and an RFC 5988 Link header (first/prev/next/last).
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, page, limit int, totalItems int64, totalPages int) {
+	h := w.Header()
+	h.Set("X-Total-Count", strconv.FormatInt(totalItems, 10))
+	h.Set("X-Page", strconv.Itoa(page))
+	h.Set("X-Total-Pages", strconv.Itoa(totalPages))
+
+	if totalPages == 0 {
+		return
+	}
+
+	pageURL := func(p int) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("limit", strconv.Itoa(limit))
+		return getBaseURL(r) + r.URL.Path + "?" + q.Encode()
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(1))}
+	if page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(min(page-1, totalPages))))
+	}
+	if page < totalPages {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(page+1)))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageURL(totalPages)))
+
+	h.Set("Link", strings.Join(links, ", "))
 }
 
 // Helper to construct dynamic base URLs (http vs https)
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"octa/internal/config"
+	"octa/internal/database"
+)
+
+func putAssetKeys(id, keys string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/console/api/assets/"+id, strings.NewReader(fmt.Sprintf(`{"keys":%q}`, keys)))
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	UpdateAssetKeys(rec, req)
+	return rec
+}
+
+// PUT enforces the same rules as PATCH: the asset must exist and the alias ceiling applies.
+func TestUpdateAssetKeysValidation(t *testing.T) {
+	if rec := putAssetKeys("no-such-asset", "orphan-key"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown asset: status = %d, want 404", rec.Code)
+	}
+	var n int64
+	database.DB.Model(&database.KeyMapping{}).Where("key = ?", "orphan-key").Count(&n)
+	if n != 0 {
+		t.Error("a key was mapped to an asset that does not exist")
+	}
+
+	createTestAsset(t, "asset-put-limit", "png", "put-limit-0")
+	limit := config.AppConfig.Image.MaxKeyLimit
+	if limit == 0 {
+		limit = DefaultMaxKeyLimit
+	}
+	keys := make([]string, limit+1)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("put-limit-%d", i)
+	}
+
+	if rec := putAssetKeys("asset-put-limit", strings.Join(keys, ",")); rec.Code != http.StatusBadRequest {
+		t.Errorf("%d keys: status = %d, want 400", len(keys), rec.Code)
+	}
+	if rec := putAssetKeys("asset-put-limit", strings.Join(keys[:limit], ",")); rec.Code != http.StatusOK {
+		t.Errorf("%d keys: status = %d, want 200: %s", limit, rec.Code, rec.Body.String())
+	}
+}
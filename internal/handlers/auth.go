@@ -11,31 +11,65 @@ import (
 	"sync"
 
 	"octa/internal/config"
+	"octa/internal/middleware"
 	"octa/pkg/utils"
 
 	"golang.org/x/time/rate"
 )
 
 // Login RATE LIMITER (Brute Force Protection)
-var loginVisitors = make(map[string]*rate.Limiter)
+type loginVisitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+var loginVisitors = make(map[string]*loginVisitor)
 var loginMu sync.Mutex
 
+// loginCleanupOnce starts the cleanup with the first LoginRateLimitMiddleware, so packages
+// importing handlers without serving the console don't run it.
+var loginCleanupOnce sync.Once
+
+func initLoginCleanup() {
+	go startLoginCleanupRoutine()
+}
+
+// startLoginCleanupRoutine drops login limiters of IPs idle for longer than the main
+// limiter's middleware.VisitorTTL, on the same interval, so the map can't grow forever.
+// An evicted IP starts over with a full burst, which an idle IP would have refilled anyway.
+func startLoginCleanupRoutine() {
+	ticker := time.NewTicker(middleware.CleanupInterval)
+	for range ticker.C {
+		loginMu.Lock()
+		for ip, v := range loginVisitors {
+			if time.Since(v.lastSeen) > middleware.VisitorTTL {
+				delete(loginVisitors, ip)
+			}
+		}
+		loginMu.Unlock()
+	}
+}
+
 // getLoginVisitor creates a strict rate limiter specifically for login endpoints.
-// Limits: 1 request/sec, Burst: 10.
+// Limits come from security.login_rate_limit (default 1 request/sec, Burst: 10).
 func getLoginVisitor(ip string) *rate.Limiter {
 	loginMu.Lock()
 	defer loginMu.Unlock()
 
-	limiter, exists := loginVisitors[ip]
+	v, exists := loginVisitors[ip]
 	if !exists {
-		limiter = rate.NewLimiter(1, 10)
-		loginVisitors[ip] = limiter
+		conf := config.AppConfig.Security.LoginRateLimit
+		v = &loginVisitor{limiter: rate.NewLimiter(rate.Limit(conf.Rate), conf.Burst)}
+		loginVisitors[ip] = v
 	}
-	return limiter
+	v.lastSeen = time.Now()
+	return v.limiter
 }
 
 // LoginRateLimitMiddleware enforces strict limits on authentication attempts.
 func LoginRateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	loginCleanupOnce.Do(initLoginCleanup)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		ip := utils.GetRealIP(r)
 
@@ -71,8 +105,9 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	passMatch := subtle.ConstantTimeCompare([]byte(creds.Password), []byte(expectedPass)) == 1
 
 	if !userMatch || !passMatch {
-		// Artificial delay to slow down brute-force scripts
-		time.Sleep(500 * time.Millisecond)
+		// Artificial delay to slow down brute-force scripts (validated at startup)
+		delay, _ := time.ParseDuration(config.AppConfig.Security.LoginRateLimit.FailureDelay)
+		time.Sleep(delay)
 		utils.WriteError(w, http.StatusUnauthorized, utils.ErrAuthInvalid, "Incorrect username or password.")
 		return
 	}
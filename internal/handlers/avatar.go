@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"image"
 	"net/http"
 	"net/url"
-	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"octa/internal/config"
 	"octa/internal/database"
@@ -17,38 +21,53 @@ import (
 	"octa/pkg/utils"
 )
 
-func buildCacheKey(prefix string, key string, query url.Values) (string, bool) {
-	// Skip caching for custom colors to prevent cache pollution (DoS protection)
-	if query.Get("bg") != "" || query.Get("color") != "" {
-		return fmtKey(prefix, key, query), false
+// buildCacheKey addresses a render by its resolved options, so requests that normalize
+// to the same output (e.g. ?size=9999 vs ?size=1024, or junk params like ?cb=123) share one entry.
+// The "<prefix>:<seed>?" head is kept so all variants of a seed stay grouped.
+func buildCacheKey(prefix string, key string, opts styles.Options, query url.Values) (string, bool) {
+	uniqueKey := cacheKeyPrefix(prefix, key) + opts.Key()
+
+	// Skip caching for custom colors to prevent cache pollution (DoS protection).
+	// A transparent background is a single variant, so it stays cacheable.
+	if bg := query.Get("bg"); (bg != "" && !styles.TransparentBackground(bg)) || query.Get("color") != "" || query.Get("ringColor") != "" {
+		return uniqueKey, false
 	}
 
-	return fmtKey(prefix, key, query), true
+	return uniqueKey, true
 }
 
-func fmtKey(prefix, key string, query url.Values) string {
-	keys := make([]string, 0, len(query))
-	for k := range query {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys) // Ensure consistent key order
-
-	var sb strings.Builder
-	sb.WriteString(prefix)
-	sb.WriteString(":")
-	sb.WriteString(key)
-	sb.WriteString("?")
-	for _, k := range keys {
-		sb.WriteString(k)
-		sb.WriteString("=")
-		sb.WriteString(query.Get(k))
-		sb.WriteString("&")
-	}
-	return sb.String()
+// DefaultGeneratedTTL is the lifetime of cached renders when cache.generated_ttl is invalid.
+const DefaultGeneratedTTL = 24 * time.Hour
+
+var (
+	generatedTTLOnce sync.Once
+	generatedTTL     time.Duration
+)
+
+// cacheGenerated stores a generated render (gen:, montage:) for cache.generated_ttl.
+// Renders depend only on the URL, so they can outlive uploaded blobs and key lookups
+// (img:, map:), which keep cache.ttl. Uploads still drop a key's gen: entries by prefix.
+func cacheGenerated(key string, data []byte) {
+	generatedTTLOnce.Do(func() {
+		ttl, err := time.ParseDuration(config.AppConfig.Cache.GeneratedTTL)
+		if err != nil || ttl <= 0 {
+			ttl = DefaultGeneratedTTL
+		}
+		generatedTTL = ttl
+	})
+	globalCache.SetWithTTL(key, data, generatedTTL)
+}
+
+// cacheKeyPrefix is the shared head of every cached variant of one seed, e.g. "gen:bob?".
+// The trailing "?" keeps "gen:bob?" from matching "gen:bobby?..." in prefix deletes.
+func cacheKeyPrefix(prefix, key string) string {
+	return prefix + ":" + key + "?"
 }
 
 // serveWithETag handles HTTP caching headers (ETag, Cache-Control).
 // Returns 304 Not Modified if client's cache is valid.
+// Cache-Control defaults to 1 day unless the caller set one (markImmutable, markDegraded).
+// HEAD requests receive the same headers without the body.
 func serveWithETag(w http.ResponseWriter, r *http.Request, data []byte, mimeType string) {
 	hash := sha256.Sum256(data)
 	etag := hex.EncodeToString(hash[:])
@@ -58,7 +77,9 @@ func serveWithETag(w http.ResponseWriter, r *http.Request, data []byte, mimeType
 	}
 
 	w.Header().Set("Content-Type", mimeType)
-	w.Header().Set("Cache-Control", "public, max-age=86400")
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	}
 	w.Header().Set("ETag", `"`+etag+`"`)
 
 	if match := r.Header.Get("If-None-Match"); match != "" {
@@ -68,6 +89,15 @@ func serveWithETag(w http.ResponseWriter, r *http.Request, data []byte, mimeType
 		}
 	}
 
+	// Explicit length avoids chunked transfer and lets HEAD report the real size.
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+
+	// HEAD: Clients only probe existence/ETag, skip the payload.
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
 	w.Write(data)
 }
 
@@ -80,39 +110,78 @@ func ServeDirectAvatar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	uniqueKey, shouldCache := buildCacheKey("gen", key, r.URL.Query())
+	if rejectDisabledFormat(w, r) {
+		return
+	}
+
+	opts := styles.ResolveOptions(key, r.URL.Query())
+	uniqueKey, shouldCache := buildCacheKey("gen", key, opts, r.URL.Query())
 
 	// Execute generation within SingleFlight to optimize concurrent requests
-	data, err, _ := requestGroup.Do(uniqueKey, func() (interface{}, error) {
+	data, err := doShared(r.Context(), uniqueKey, func() (interface{}, error) {
 		if shouldCache {
 			if cached, ok := globalCache.Get(uniqueKey); ok {
-				return cached, nil
+				return genResult{Data: cached, MimeType: opts.MimeType()}, nil
 			}
 		}
 
-		genData, _, err := styles.GenerateImageBytes(key, r.URL.Query())
+		res, err := generateAvatar(opts)
 
 		if err != nil {
 			return nil, err
 		}
 
-		if shouldCache {
-			globalCache.Set(uniqueKey, genData)
+		if shouldCache && !res.Degraded {
+			cacheGenerated(uniqueKey, res.Data)
 		}
-		return genData, nil
+		return res, nil
 	})
 
 	if err != nil {
+		if errors.Is(err, errGenerationBusy) {
+			writeBusy(w)
+			return
+		}
+		if writeSharedTimeout(w, err) {
+			return
+		}
 		utils.WriteError(w, http.StatusInternalServerError, utils.ErrImageGenerationFailed, "Failed to generate avatar image.")
 		return
 	}
 
-	mime := "image/png"
-	if r.URL.Query().Get("format") == "svg" || r.URL.Query().Get("type") == "svg" {
-		mime = "image/svg+xml"
+	res := data.(genResult)
+	if res.Degraded {
+		markDegraded(w)
 	}
+	// Same seed + params always render the same bytes.
+	markImmutable(w)
+
+	// Inline variant for SSR/data layers: same cached bytes, only the encoding differs.
+	w.Header().Add("Vary", "Accept")
+	if wantsBase64(r) {
+		writeBase64JSON(w, res.Data, res.MimeType)
+		return
+	}
+
+	serveWithETag(w, r, res.Data, res.MimeType)
+}
+
+// wantsBase64 reports whether the client asked for a JSON data URI instead of raw bytes
+// (?encode=base64 or Accept: application/json).
+func wantsBase64(r *http.Request) bool {
+	return r.URL.Query().Get("encode") == "base64" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+}
 
-	serveWithETag(w, r, data.([]byte), mime)
+// writeBase64JSON responds with {"data":"data:<mime>;base64,...","mime":"<mime>"}.
+func writeBase64JSON(w http.ResponseWriter, data []byte, mimeType string) {
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"data": "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data),
+		"mime": mimeType,
+	})
 }
 
 // ServeUserAvatar serves avatars from DB if available, otherwise falls back to generator.
@@ -131,16 +200,26 @@ func ServeUserAvatar(w http.ResponseWriter, r *http.Request) {
 	if cachedIDBytes, ok := globalCache.Get(mapCacheKey); ok {
 		targetImageID = string(cachedIDBytes)
 	} else {
-		var mapping database.KeyMapping
+		// Mapping Lookup: Coalesce a cold-cache stampede on one key into a single query.
+		id, err, _ := requestGroup.Do(mapCacheKey, func() (interface{}, error) {
+			if cachedIDBytes, ok := globalCache.Get(mapCacheKey); ok {
+				return string(cachedIDBytes), nil
+			}
+
+			var mapping database.KeyMapping
+			if err := database.ReadDB.Select("image_id").First(&mapping, "key = ?", key).Error; err != nil {
+				return nil, err
+			}
 
-		if err := database.DB.Select("image_id").First(&mapping, "key = ?", key).Error; err != nil {
+			globalCache.Set(mapCacheKey, []byte(mapping.ImageID))
+			return mapping.ImageID, nil
+		})
 
+		if err != nil {
 			serveGeneratorFallback(w, r, key)
 			return
 		}
-		targetImageID = mapping.ImageID
-
-		globalCache.Set(mapCacheKey, []byte(targetImageID))
+		targetImageID = id.(string)
 	}
 
 	imgCacheKey := "img:" + targetImageID
@@ -148,66 +227,94 @@ func ServeUserAvatar(w http.ResponseWriter, r *http.Request) {
 	// DB Fetch
 	sfDBGroupKey := "fetch_img:" + targetImageID
 	data, dbError, _ := requestGroup.Do(sfDBGroupKey, func() (interface{}, error) {
-		// Double-check cache inside lock
+		// Double-check cache inside lock (the cache keeps bytes only, so sniff the type)
 		if cached, ok := globalCache.Get(imgCacheKey); ok {
-			return cached, nil
-		}
-
-		var mapping database.KeyMapping
-		if err := database.DB.First(&mapping, "key = ?", key).Error; err != nil {
-			return nil, err // Not found
+			return storedImage{Data: cached, MimeType: http.DetectContentType(cached)}, nil
 		}
 
 		var imgModel database.Image
-		if err := database.DB.Select("data").First(&imgModel, "id = ?", mapping.ImageID).Error; err != nil {
+		if err := database.ReadDB.Select("data", "format").First(&imgModel, "id = ?", targetImageID).Error; err != nil {
 			return nil, err
 		}
 
 		globalCache.Set(imgCacheKey, imgModel.Data)
-		return imgModel.Data, nil
+		return storedImage{Data: imgModel.Data, MimeType: formatMimeType(imgModel.Format, imgModel.Data)}, nil
 	})
 
 	if dbError != nil {
 		serveGeneratorFallback(w, r, key)
+		return
 	}
 
-	serveWithETag(w, r, data.([]byte), "image/png")
+	img := negotiateWebP(w, r, targetImageID, data.(storedImage))
+	serveWithETag(w, r, img.Data, img.MimeType)
 
 }
 
+// storedImage carries an uploaded asset and its real Content-Type through SingleFlight.
+type storedImage struct {
+	Data     []byte
+	MimeType string
+}
+
+// formatMimeType maps the Image.Format column (as reported by image.DecodeConfig) to a MIME type.
+// Unknown formats fall back to content sniffing.
+func formatMimeType(format string, data []byte) string {
+	switch strings.ToLower(format) {
+	case "jpeg", "jpg":
+		return "image/jpeg"
+	case "png":
+		return "image/png"
+	case "gif":
+		return "image/gif"
+	case "webp":
+		return "image/webp"
+	}
+	return http.DetectContentType(data)
+}
+
 func serveGeneratorFallback(w http.ResponseWriter, r *http.Request, key string) {
+	if rejectDisabledFormat(w, r) {
+		return
+	}
+
 	// Generator Fallback (If not in DB)
-	uniqueKey, shouldCache := buildCacheKey("gen", key, r.URL.Query())
+	opts := styles.ResolveOptions(key, r.URL.Query())
+	uniqueKey, shouldCache := buildCacheKey("gen", key, opts, r.URL.Query())
 
 	genRes, genErr, _ := requestGroup.Do(uniqueKey, func() (interface{}, error) {
 		if shouldCache {
 			if cached, ok := globalCache.Get(uniqueKey); ok {
-				return cached, nil
+				return genResult{Data: cached, MimeType: opts.MimeType()}, nil
 			}
 		}
 
-		genData, _, err := styles.GenerateImageBytes(uniqueKey, r.URL.Query())
+		res, err := generateAvatar(opts)
 
 		if err != nil {
 			return nil, err
 		}
-		if shouldCache {
-			globalCache.Set(uniqueKey, genData)
+		if shouldCache && !res.Degraded {
+			cacheGenerated(uniqueKey, res.Data)
 		}
-		return genData, nil
+		return res, nil
 	})
 
 	if genErr != nil {
+		if errors.Is(genErr, errGenerationBusy) {
+			writeBusy(w)
+			return
+		}
 		utils.WriteError(w, http.StatusInternalServerError, utils.ErrImageGenerationFailed, "Unable to generate fallback avatar.")
 		return
 	}
 
-	mimeType := "image/png"
-	if r.URL.Query().Get("format") == "svg" {
-		mimeType = "image/svg+xml"
+	res := genRes.(genResult)
+	if res.Degraded {
+		markDegraded(w)
 	}
 
-	serveWithETag(w, r, genRes.([]byte), mimeType)
+	serveWithETag(w, r, res.Data, res.MimeType)
 }
 
 // GITHUB AVATAR (/avatar/github/:username)
@@ -228,11 +335,17 @@ func GithubAvatarHandler(w http.ResponseWriter, r *http.Request) {
 		avatarSize = styles.DefaultAvatarSize
 	}
 
-	data, err, _ := requestGroup.Do(uniqueKey, func() (interface{}, error) {
+	data, err := doShared(r.Context(), uniqueKey, func() (interface{}, error) {
 	
+		// Only real GitHub images are cached, so the type can be sniffed
 		if cached, ok := globalCache.Get(uniqueKey); ok {
-			return cached, nil
+			return githubResult{Data: cached, MimeType: http.DetectContentType(cached)}, nil
+		}
+
+		if !acquireGitHubSlot() {
+			return nil, errGitHubBusy
 		}
+		defer releaseGitHubSlot()
 
 		// genParams := url.Values{}
 		// genParams.Set("size", fmt.Sprintf("%d", styles.DefaultAvatarSize)) // "360"
@@ -246,24 +359,18 @@ func GithubAvatarHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if err != nil || ghUser.AvatarURL == "" {
-			genData, _, genErr := styles.GenerateImageBytes(fallbackName, nil)
-			if genErr == nil {
-				globalCache.Set(uniqueKey, genData)
-			}
-			return genData, genErr
+			return githubFallback(fallbackName)
 		}
 
 		// Download Image
-		imgResp, err := http.Get(ghUser.AvatarURL)
-		if err != nil || imgResp.StatusCode != 200 {
-			genData, _, genErr := styles.GenerateImageBytes(fallbackName, nil)
-
-			if genErr == nil {
-				globalCache.Set(uniqueKey, genData)
-			}
-			return genData, genErr
+		imgResp, err := githubClient.Get(ghUser.AvatarURL)
+		if err != nil {
+			return githubFallback(fallbackName)
 		}
 		defer imgResp.Body.Close()
+		if imgResp.StatusCode != 200 {
+			return githubFallback(fallbackName)
+		}
 
 	
 		img, _, err := image.Decode(imgResp.Body)
@@ -274,26 +381,59 @@ func GithubAvatarHandler(w http.ResponseWriter, r *http.Request) {
 		procOpts := utils.ProcessOptions{
 			Mode:    "fit",
 			Size:    avatarSize,
-			Quality: 80,
+			Quality: imageQuality(),
 		}
 
 		// Process
-		processedBuf, _, _, err := utils.ProcessImage(img, procOpts)
+		processed, err := utils.ProcessImage(img, procOpts)
 		if err != nil {
 			return nil, err
 		}
 
-		finalBytes := processedBuf.Bytes()
+		finalBytes := processed.Data
 
 		globalCache.Set(uniqueKey, finalBytes)
 
-		return finalBytes, nil
+		return githubResult{Data: finalBytes, MimeType: formatMimeType(processed.Format, finalBytes)}, nil
 	})
 
 	if err != nil {
+		if errors.Is(err, errGenerationBusy) {
+			writeBusy(w)
+			return
+		}
+		if errors.Is(err, errGitHubBusy) {
+			serveGitHubBusyFallback(w, r, username)
+			return
+		}
+		if writeSharedTimeout(w, err) {
+			return
+		}
 		utils.WriteError(w, http.StatusBadGateway, utils.ErrUpstreamFailed, "Failed to process avatar.")
 		return
 	}
 
-	serveWithETag(w, r, data.([]byte), "image/jpeg")
+	res := data.(githubResult)
+	if res.Fallback {
+		markDegraded(w)
+	}
+	serveWithETag(w, r, res.Data, res.MimeType)
+}
+
+// githubResult carries a GitHub avatar (or the generated stand-in) through SingleFlight.
+type githubResult struct {
+	Data     []byte
+	MimeType string
+	Fallback bool // Generated because GitHub failed; served no-store and never cached
+}
+
+// githubFallback renders a generated avatar while GitHub is unreachable or has no avatar.
+// It is not cached under gh:, so the next request tries GitHub again.
+func githubFallback(name string) (githubResult, error) {
+	opts := styles.ResolveOptions(name, nil)
+	res, err := generateAvatar(opts)
+	if err != nil {
+		return githubResult{}, err
+	}
+	return githubResult{Data: res.Data, MimeType: res.MimeType, Fallback: true}, nil
 }
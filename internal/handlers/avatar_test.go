@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"octa/pkg/generator/styles"
+)
+
+func TestServeUserAvatarContentType(t *testing.T) {
+	tests := []struct {
+		format   string
+		wantMime string
+	}{
+		{"png", "image/png"},
+		{"jpeg", "image/jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			key := "content-type-" + tt.format
+			data := createTestAsset(t, "asset-ct-"+tt.format, tt.format, key)
+
+			// The first request reads the Format column, the second is served from the cache.
+			for _, pass := range []string{"db", "cache"} {
+				rec := httptest.NewRecorder()
+				ServeUserAvatar(rec, httptest.NewRequest(http.MethodGet, "/u/"+key, nil))
+
+				if rec.Code != http.StatusOK {
+					t.Fatalf("%s: status = %d, want 200", pass, rec.Code)
+				}
+				if got := rec.Header().Get("Content-Type"); got != tt.wantMime {
+					t.Errorf("%s: Content-Type = %q, want %q", pass, got, tt.wantMime)
+				}
+				if !bytes.Equal(rec.Body.Bytes(), data) {
+					t.Errorf("%s: body differs from the stored asset", pass)
+				}
+			}
+		})
+	}
+}
+
+// Once both pools are full, degraded renders must shed load too instead of rendering unbounded.
+func TestGenerateDegradedIsBounded(t *testing.T) {
+	genGuardOnce.Do(initGenGuard)
+	for i := 0; i < cap(degradedGuard); i++ {
+		degradedGuard <- struct{}{}
+	}
+	t.Cleanup(func() {
+		for len(degradedGuard) > 0 {
+			<-degradedGuard
+		}
+	})
+
+	if _, err := generateDegraded(styles.ResolveOptions("busy", nil)); !errors.Is(err, errGenerationBusy) {
+		t.Fatalf("generateDegraded with a full pool: err = %v, want errGenerationBusy", err)
+	}
+
+	<-degradedGuard
+	opts := styles.ResolveOptions("busy", url.Values{"format": {"ico"}})
+	res, err := generateDegraded(opts)
+	if err != nil {
+		t.Fatalf("generateDegraded: %v", err)
+	}
+	if !res.Degraded || res.MimeType != "image/png" || http.DetectContentType(res.Data) != "image/png" {
+		t.Errorf("degraded ico render: Degraded=%v MimeType=%q sniffed=%q, want a PNG",
+			res.Degraded, res.MimeType, http.DetectContentType(res.Data))
+	}
+}
+
+// format=avif is opt-in on both routes that generate, including the /u/ fallback for unknown keys.
+func TestAvifDisabledIsRejected(t *testing.T) {
+	routes := map[string]http.HandlerFunc{
+		"/avatar/octa?format=avif":   ServeDirectAvatar,
+		"/u/no-such-key?format=avif": ServeUserAvatar,
+	}
+	for target, handler := range routes {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", target, rec.Code)
+		}
+	}
+}
@@ -6,27 +6,22 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"sync"
 	"time"
 
 	"octa/internal/database"
 	"octa/pkg/utils"
 )
 
-var (
-	backupMutex sync.Mutex
-)
-
 // BackupHandler generates a point-in-time snapshot of the SQLite database.
 // It is protected by AuthMiddleware to ensure only authorized admins can trigger it.
 func BackupHandler(w http.ResponseWriter, r *http.Request) {
 
-	// Ensure only one backup runs at a time to prevent resource exhaustion.
-	if !backupMutex.TryLock() {
-		utils.WriteError(w, http.StatusTooManyRequests, utils.ErrBackupConcurrencyLimit, "Another backup is currently in progress.")
+	// Ensure only one backup (or VACUUM/checkpoint) runs at a time to prevent resource exhaustion.
+	if !database.MaintenanceMu.TryLock() {
+		utils.WriteError(w, http.StatusTooManyRequests, utils.ErrBackupConcurrencyLimit, "Another backup or database maintenance is currently in progress.")
 		return
 	}
-	defer backupMutex.Unlock()
+	defer database.MaintenanceMu.Unlock()
 
 	// Even with a cookie, we check if the request actually came from our own admin dashboard.
 	referer := r.Header.Get("Referer")
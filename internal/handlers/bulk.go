@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"octa/internal/config"
+	"octa/internal/database"
+	"octa/pkg/utils"
+)
+
+const (
+	MaxBulkKeyItems    = 500     // Assets per bulk-keys request
+	MaxBulkKeyBodySize = 1 << 20 // 1MB of JSON
+)
+
+// BulkKeysItem replaces every key of one asset, like PUT /console/api/assets/{id}.
+type BulkKeysItem struct {
+	ID   string   `json:"id"`
+	Keys []string `json:"keys"` // e.g. ["team/alice", "alice"]; empty clears all keys
+}
+
+// BulkKeysResult is the outcome of one item. Keys are the stored keys after a successful apply
+// ([] if the item cleared them); items that were not applied report null.
+type BulkKeysResult struct {
+	ID     string   `json:"id"`
+	Status string   `json:"status"` // "updated", "failed" or "not_applied"
+	Keys   []string `json:"keys"`
+	Code   string   `json:"code,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// BulkUpdateAssetKeys replaces the keys of many assets in one transaction.
+// All items apply or none do; the response lists the outcome per item either way.
+// Keys may move between assets of the same batch (swaps are fine).
+// POST /console/api/assets/bulk-keys
+func BulkUpdateAssetKeys(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBulkKeyBodySize)
+
+	var items []BulkKeysItem
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Invalid JSON body: expected an array of {id, keys}.")
+		return
+	}
+	if len(items) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "At least one item is required.")
+		return
+	}
+	if len(items) > MaxBulkKeyItems {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid,
+			fmt.Sprintf("Too many items: a bulk update can change at most %d assets.", MaxBulkKeyItems))
+		return
+	}
+
+	maxKeyLimit := config.AppConfig.Image.MaxKeyLimit
+	if maxKeyLimit == 0 {
+		maxKeyLimit = DefaultMaxKeyLimit
+	}
+
+	results := make([]BulkKeysResult, len(items))
+	keysByItem := make([][]string, len(items))
+	ids := make([]string, 0, len(items))
+	seenIDs := make(map[string]bool, len(items))
+	failed := false
+
+	fail := func(i int, code, msg string) {
+		results[i].Status, results[i].Code, results[i].Error = "failed", code, msg
+		failed = true
+	}
+
+	// 1. Validate everything before touching the database
+	for i, item := range items {
+		results[i] = BulkKeysResult{ID: item.ID, Status: "not_applied"}
+
+		switch {
+		case item.ID == "":
+			fail(i, utils.ErrRequestInvalid, "Asset ID is required.")
+			continue
+		case seenIDs[item.ID]:
+			fail(i, utils.ErrRequestInvalid, "Asset appears more than once in the batch.")
+			continue
+		}
+		seenIDs[item.ID] = true
+
+		keys, msg := cleanKeyList(item.Keys)
+		if msg == "" && len(keys) > maxKeyLimit {
+			msg = fmt.Sprintf("Too many keys: an asset can have at most %d.", maxKeyLimit)
+		}
+		if msg != "" {
+			fail(i, utils.ErrValidationInvalidFormat, msg)
+			continue
+		}
+
+		keysByItem[i] = keys
+		ids = append(ids, item.ID)
+	}
+
+	if failed {
+		writeBulkKeysFailure(w, http.StatusBadRequest, results)
+		return
+	}
+
+	// Serialize with uploads: a large batch is one long write transaction.
+	if err := acquireDBWrite(r.Context()); err != nil {
+		writeWriteBusy(w)
+		return
+	}
+	defer releaseDBWrite()
+
+	tx := database.DB.WithContext(r.Context()).Begin()
+	if tx.Error != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to start transaction.")
+		return
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	// 2. Every asset must exist
+	var existingIDs []string
+	if err := tx.Model(&database.Image{}).Where("id IN ?", ids).Pluck("id", &existingIDs).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to read assets.")
+		return
+	}
+	exists := make(map[string]bool, len(existingIDs))
+	for _, id := range existingIDs {
+		exists[id] = true
+	}
+	for i, item := range items {
+		if !exists[item.ID] {
+			fail(i, utils.ErrResourceNotFound, "Asset not found.")
+		}
+	}
+	if failed {
+		writeBulkKeysFailure(w, http.StatusNotFound, results)
+		return
+	}
+
+	// 3. Drop current keys first, so keys can move between assets in the batch
+	var oldKeys []string
+	if err := tx.Model(&database.KeyMapping{}).Where("image_id IN ?", ids).Pluck("key", &oldKeys).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to read asset keys.")
+		return
+	}
+	if err := tx.Where("image_id IN ?", ids).Delete(&database.KeyMapping{}).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to reset asset keys.")
+		return
+	}
+
+	// 4. Conflicts: keys still owned by assets outside the batch, or claimed twice within it
+	var allNew []string
+	for _, keys := range keysByItem {
+		allNew = append(allNew, keys...)
+	}
+	var taken []string
+	if len(allNew) > 0 {
+		if err := tx.Model(&database.KeyMapping{}).Where("key IN ?", allNew).Pluck("key", &taken).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to check for conflicts.")
+			return
+		}
+	}
+	inUse := make(map[string]bool, len(taken))
+	for _, k := range taken {
+		inUse[k] = true
+	}
+	claimed := make(map[string]string, len(allNew))
+	for i, keys := range keysByItem {
+		for _, k := range keys {
+			if inUse[k] {
+				fail(i, utils.ErrResourceConflict, fmt.Sprintf("Key '%s' is already in use.", k))
+				break
+			}
+			if owner, dup := claimed[k]; dup {
+				fail(i, utils.ErrResourceConflict, fmt.Sprintf("Key '%s' is also assigned to asset %s in this batch.", k, owner))
+				break
+			}
+			claimed[k] = items[i].ID
+		}
+	}
+	if failed {
+		writeBulkKeysFailure(w, http.StatusConflict, results)
+		return
+	}
+
+	// 5. Apply
+	mappings := make([]database.KeyMapping, 0, len(allNew))
+	for i, keys := range keysByItem {
+		for _, k := range keys {
+			mappings = append(mappings, database.KeyMapping{Key: k, ImageID: items[i].ID})
+		}
+	}
+	if len(mappings) > 0 {
+		if err := tx.CreateInBatches(mappings, 500).Error; err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to store asset keys.")
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Transaction failed.")
+		return
+	}
+	committed = true
+
+	invalidateKeyCache(append(oldKeys, allNew...))
+
+	for i := range results {
+		results[i].Status = "updated"
+		results[i].Keys = keysByItem[i]
+		if results[i].Keys == nil {
+			results[i].Keys = []string{}
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"action":  "bulk_updated",
+		"applied": true,
+		"updated": len(items),
+		"results": results,
+	})
+}
+
+// writeBulkKeysFailure reports a rejected batch. Nothing was applied.
+func writeBulkKeysFailure(w http.ResponseWriter, status int, results []BulkKeysResult) {
+	utils.WriteJSON(w, status, map[string]interface{}{
+		"status":  "failed",
+		"applied": false,
+		"message": "No changes were applied: fix the failed items and retry the batch.",
+		"results": results,
+	})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"octa/internal/config"
+	"octa/pkg/utils"
+)
+
+// DefaultCoalesceTimeout bounds how long a request waits on a shared (SingleFlight) result.
+const DefaultCoalesceTimeout = 10 * time.Second
+
+// errCoalesceTimeout: the shared work did not finish in time. Maps to 504.
+var errCoalesceTimeout = errors.New("timed out waiting for shared request")
+
+var (
+	coalesceOnce    sync.Once
+	coalesceTimeout time.Duration
+)
+
+func initCoalesceTimeout() {
+	timeout, err := time.ParseDuration(config.AppConfig.Image.CoalesceTimeout)
+	if err != nil || timeout <= 0 {
+		timeout = DefaultCoalesceTimeout
+	}
+	coalesceTimeout = timeout
+}
+
+// doShared is requestGroup.Do with a deadline: if the leader hangs (e.g. a slow upstream),
+// callers give up after image.coalesce_timeout instead of blocking forever.
+// The leader keeps running, so a late result still lands in the cache for the next request.
+func doShared(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	coalesceOnce.Do(initCoalesceTimeout)
+
+	ch := requestGroup.DoChan(key, fn)
+
+	timer := time.NewTimer(coalesceTimeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-timer.C:
+		return nil, errCoalesceTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// writeSharedTimeout answers a doShared deadline error. Canceled requests get no body, the client is gone.
+// Returns false if err is not a deadline error.
+func writeSharedTimeout(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, errCoalesceTimeout):
+		utils.WriteError(w, http.StatusGatewayTimeout, utils.ErrServerTimeout, "Timed out waiting for the avatar.")
+		return true
+	case errors.Is(err, context.Canceled):
+		return true
+	}
+	return false
+}
@@ -17,7 +17,12 @@ func CoreDeleteAsset(ctx context.Context, assetID string) error {
 		return tx.Error
 	}
 
-	defer tx.Rollback()
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
 
 	var sizeToDelete int64
 	if err := tx.Model(&database.Image{}).Where("id = ?", assetID).Select("size").Scan(&sizeToDelete).Error; err != nil {
@@ -48,6 +53,7 @@ func CoreDeleteAsset(ctx context.Context, assetID string) error {
 	if err := tx.Commit().Error; err != nil {
 		return fmt.Errorf("transaction commit failed: %w", err)
 	}
+	committed = true
 
 	appinfo.RemoveAsset(sizeToDelete)
 
@@ -55,9 +61,8 @@ func CoreDeleteAsset(ctx context.Context, assetID string) error {
 		for _, k := range keys {
 			globalCache.Delete("map:" + k)
 		}
-
-		globalCache.Delete("img:" + assetID)
 	}
+	invalidateImageCache(assetID)
 
 	return nil
 }
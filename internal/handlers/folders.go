@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"octa/internal/database"
+	"octa/pkg/utils"
+)
+
+// FolderNode is one level of the folder tree implied by keys like "nature/mountain-12".
+type FolderNode struct {
+	Name     string        `json:"name"`  // "mountain"
+	Path     string        `json:"path"`  // "nature/mountain/"
+	Count    int           `json:"count"` // Keys in this folder and all its subfolders
+	Children []*FolderNode `json:"children"`
+}
+
+// ListFolders derives the folder tree from key prefixes with per-folder key counts.
+// Optional ?prefix=nature/ returns only that subtree.
+// GET /console/api/folders
+func ListFolders(w http.ResponseWriter, r *http.Request) {
+	prefix := normalizeFolder(r.URL.Query().Get("prefix"))
+
+	var keys []string
+	err := database.WhereKeyPrefix(database.ReadDB.WithContext(r.Context()).Model(&database.KeyMapping{}), prefix).
+		Pluck("key", &keys).Error
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to read folders.")
+		return
+	}
+
+	root := &FolderNode{Path: prefix, Children: []*FolderNode{}}
+	nodes := map[string]*FolderNode{prefix: root}
+
+	for _, k := range keys {
+		rest := strings.TrimPrefix(k, prefix)
+		parts := strings.Split(rest, "/")
+		parts = parts[:len(parts)-1] // Last segment is the file name
+
+		parent := root
+		path := prefix
+		for _, part := range parts {
+			if part == "" {
+				break // "a//b" style keys: stop at the empty segment
+			}
+			path += part + "/"
+			node, ok := nodes[path]
+			if !ok {
+				node = &FolderNode{Name: part, Path: path, Children: []*FolderNode{}}
+				nodes[path] = node
+				parent.Children = append(parent.Children, node)
+			}
+			node.Count++
+			parent = node
+		}
+		root.Count++
+	}
+
+	sortFolderTree(root)
+
+	utils.WriteJSON(w, http.StatusOK, root)
+}
+
+// normalizeFolder turns "nature", "/nature" or "nature/" into "nature/". Empty stays empty (root).
+func normalizeFolder(folder string) string {
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	if folder == "" {
+		return ""
+	}
+	return strings.ToLower(folder) + "/"
+}
+
+func sortFolderTree(n *FolderNode) {
+	sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+	for _, c := range n.Children {
+		sortFolderTree(c)
+	}
+}
+
+// MaxMoveKeys bounds a single move so one request can't hold the writer lock for long.
+const MaxMoveKeys = 5000
+
+type MoveAssetsRequest struct {
+	From string `json:"from"` // e.g. "nature/"
+	To   string `json:"to"`   // e.g. "landscapes/"
+}
+
+// MoveAssets renames every key starting with From so it starts with To instead.
+// The whole move is one transaction: any collision with a key outside the moved set aborts it with 409.
+// POST /console/api/assets/move
+func MoveAssets(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 2048)
+
+	var req MoveAssetsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Invalid JSON body.")
+		return
+	}
+
+	from := strings.ToLower(strings.TrimLeft(strings.TrimSpace(req.From), "/"))
+	to := strings.ToLower(strings.TrimLeft(strings.TrimSpace(req.To), "/"))
+	if from == "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "'from' prefix is required.")
+		return
+	}
+	if from == to {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "'from' and 'to' are identical.")
+		return
+	}
+
+	// Serialize with uploads: up to MaxMoveKeys renames in one write transaction.
+	if err := acquireDBWrite(r.Context()); err != nil {
+		writeWriteBusy(w)
+		return
+	}
+	defer releaseDBWrite()
+
+	tx := database.DB.WithContext(r.Context()).Begin()
+	if tx.Error != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to start transaction.")
+		return
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	var mappings []database.KeyMapping
+	if err := database.WhereKeyPrefix(tx, from).Limit(MaxMoveKeys + 1).Find(&mappings).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to read keys.")
+		return
+	}
+
+	if len(mappings) == 0 {
+		utils.WriteError(w, http.StatusNotFound, utils.ErrResourceNotFound, "No keys match the given prefix.")
+		return
+	}
+	if len(mappings) > MaxMoveKeys {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid,
+			fmt.Sprintf("Too many keys: a single move can rename at most %d.", MaxMoveKeys))
+		return
+	}
+
+	oldKeys := make([]string, 0, len(mappings))
+	newKeys := make([]string, 0, len(mappings))
+	moving := make(map[string]bool, len(mappings))
+	for _, m := range mappings {
+		newKey := utils.NormalizeKey(to + strings.TrimPrefix(m.Key, from))
+		if !utils.IsValidKeyFormat(newKey) {
+			utils.WriteError(w, http.StatusBadRequest, utils.ErrValidationInvalidFormat,
+				fmt.Sprintf("Key '%s' would become invalid key '%s'.", m.Key, newKey))
+			return
+		}
+		if len(newKey) > MaxKeyLength {
+			utils.WriteError(w, http.StatusBadRequest, utils.ErrValidationInvalidFormat,
+				fmt.Sprintf("Key '%s' would become '%s', longer than %d characters.", m.Key, newKey, MaxKeyLength))
+			return
+		}
+		oldKeys = append(oldKeys, m.Key)
+		newKeys = append(newKeys, newKey)
+		moving[m.Key] = true
+	}
+
+	// Collisions: a target key that exists and is not itself being moved away.
+	var existing []string
+	if err := tx.Model(&database.KeyMapping{}).Where("key IN ?", newKeys).Pluck("key", &existing).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to check for conflicts.")
+		return
+	}
+	var conflicts []string
+	for _, k := range existing {
+		if !moving[k] {
+			conflicts = append(conflicts, k)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		if len(conflicts) > 5 {
+			conflicts = append(conflicts[:5], "...")
+		}
+		utils.WriteError(w, http.StatusConflict, utils.ErrResourceConflict,
+			fmt.Sprintf("Target keys already in use: %s", strings.Join(conflicts, ", ")))
+		return
+	}
+
+	// Delete-then-insert avoids ordering issues when new keys overlap old ones (e.g. "a/" -> "a/b/").
+	if err := tx.Where("key IN ?", oldKeys).Delete(&database.KeyMapping{}).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to move keys.")
+		return
+	}
+	renamed := make([]database.KeyMapping, len(mappings))
+	for i, m := range mappings {
+		renamed[i] = database.KeyMapping{Key: newKeys[i], ImageID: m.ImageID, CreatedAt: m.CreatedAt}
+	}
+	if err := tx.CreateInBatches(renamed, 500).Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to move keys.")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Transaction failed.")
+		return
+	}
+	committed = true
+
+	invalidateMovedFolder(from, to, newKeys)
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"action": "moved",
+		"from":   from,
+		"to":     to,
+		"moved":  len(mappings),
+	})
+}
+
+// invalidateMovedFolder drops the cached mappings and generated fallbacks under both folders
+// with one prefix delete each, instead of one per key. Moving to the root ("") would turn the
+// target prefix into the whole cache, so the new keys are invalidated one by one instead.
+func invalidateMovedFolder(from, to string, newKeys []string) {
+	if globalCache == nil {
+		return
+	}
+	prefixes := []string{"map:" + from, "gen:" + from}
+	if to != "" {
+		prefixes = append(prefixes, "map:"+to, "gen:"+to)
+	} else {
+		invalidateKeyCache(newKeys)
+	}
+	globalCache.DeletePrefix(prefixes...)
+}
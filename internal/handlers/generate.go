@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"octa/internal/config"
+	"octa/pkg/generator/styles"
+	"octa/pkg/utils"
+)
+
+const (
+	// DefaultGenerationTimeout is how long a render waits for a free slot before giving up.
+	DefaultGenerationTimeout = 3 * time.Second
+
+	// DefaultDegradeThreshold: Fraction of busy render slots that switches to degraded output.
+	DefaultDegradeThreshold = 0.8
+
+	// DegradedAvatarSize: Cheap render size used under load. Browsers upscale it via CSS.
+	DegradedAvatarSize = 64
+
+	// DegradedConcurrencyRatio sizes the degraded pool as a fraction of the render pool (at least 1 slot).
+	DegradedConcurrencyRatio = 0.25
+)
+
+// genResult carries rendered bytes and their Content-Type through SingleFlight.
+// Degraded results are load-shedding placeholders and must never be cached.
+type genResult struct {
+	Data     []byte
+	MimeType string // Degraded renders may differ from the requested format
+	Degraded bool
+}
+
+// errGenerationBusy is returned when every render slot stays occupied past the timeout.
+var errGenerationBusy = errors.New("avatar generation capacity exhausted")
+
+// genGuard acts as a semaphore bounding concurrent avatar renders.
+// Rendering is CPU-bound; a flood of unique seeds (cache misses) would otherwise saturate every core.
+// degradedGuard bounds the cheap renders served once genGuard is saturated, so load
+// shedding never turns into unbounded rendering.
+var (
+	genGuard      chan struct{}
+	degradedGuard chan struct{}
+	genTimeout    time.Duration
+	genGuardOnce  sync.Once
+)
+
+func initGenGuard() {
+	limit := config.AppConfig.Image.MaxConcurrentGeneration
+	if limit <= 0 {
+		limit = runtime.NumCPU() * 2
+	}
+	genGuard = make(chan struct{}, limit)
+	degradedGuard = make(chan struct{}, max(1, int(float64(limit)*DegradedConcurrencyRatio)))
+
+	timeout, err := time.ParseDuration(config.AppConfig.Image.GenerationTimeout)
+	if err != nil || timeout <= 0 {
+		timeout = DefaultGenerationTimeout
+	}
+	genTimeout = timeout
+}
+
+// generateAvatar renders an avatar while holding a generation slot.
+// Returns errGenerationBusy if no slot frees up within image.generation_timeout.
+//
+// With image.degrade_under_load enabled, a saturated pool serves a small (cheap) variant
+// instead of queueing or failing, keeping avatars available during traffic spikes.
+func generateAvatar(opts styles.Options) (genResult, error) {
+	genGuardOnce.Do(initGenGuard)
+
+	degrade := config.AppConfig.Image.DegradeUnderLoad
+	if degrade && isGenerationOverloaded() {
+		return generateDegraded(opts)
+	}
+
+	timer := time.NewTimer(genTimeout)
+	defer timer.Stop()
+
+	select {
+	case genGuard <- struct{}{}:
+	case <-timer.C:
+		if degrade {
+			return generateDegraded(opts)
+		}
+		return genResult{}, errGenerationBusy
+	}
+	defer func() { <-genGuard }() // Release slot
+
+	data, _, err := styles.RenderAvatar(opts)
+	return genResult{Data: data, MimeType: opts.MimeType()}, err
+}
+
+// isGenerationOverloaded reports whether busy render slots crossed image.degrade_threshold.
+func isGenerationOverloaded() bool {
+	threshold := config.AppConfig.Image.DegradeThreshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultDegradeThreshold
+	}
+	return float64(len(genGuard)) >= float64(cap(genGuard))*threshold
+}
+
+// generateDegraded renders the same avatar at DegradedAvatarSize in its own small pool.
+// It never waits: with that pool full too, it returns errGenerationBusy.
+// Raster output is forced to PNG (no WASM encode, no ICO frame set); SVG stays SVG, it is cheaper still.
+func generateDegraded(opts styles.Options) (genResult, error) {
+	select {
+	case degradedGuard <- struct{}{}:
+	default:
+		return genResult{}, errGenerationBusy
+	}
+	defer func() { <-degradedGuard }()
+
+	opts.Size = DegradedAvatarSize
+	if opts.Format != "svg" {
+		opts.Format = "png"
+	}
+
+	data, _, err := styles.RenderAvatar(opts)
+	return genResult{Data: data, MimeType: opts.MimeType(), Degraded: true}, err
+}
+
+// markDegraded keeps CDNs and browsers from pinning a load-shedding placeholder.
+func markDegraded(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Octa-Degraded", "true")
+}
+
+// immutableCacheControl is for responses fully determined by the URL (seed + params).
+// Anything backed by mutable state (uploads, key mappings, GitHub) keeps serveWithETag's 1-day default.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// markImmutable lets CDNs and browsers keep a deterministic render for a year without revalidating.
+// It never overrides an earlier Cache-Control (e.g. markDegraded's no-store).
+func markImmutable(w http.ResponseWriter) {
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", immutableCacheControl)
+	}
+}
+
+// rejectDisabledFormat answers 400 for format=avif while image.allow_avif is off. AVIF is opt-in:
+// say so instead of silently serving PNG. Returns true if the response was written.
+func rejectDisabledFormat(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Query().Get("format") == "avif" && !config.AppConfig.Image.AllowAVIF {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "format=avif is disabled on this server (image.allow_avif).")
+		return true
+	}
+	return false
+}
+
+// writeBusy signals backpressure: clients should retry shortly instead of piling up.
+func writeBusy(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	utils.WriteError(w, http.StatusServiceUnavailable, utils.ErrServerBusy, "Server is busy generating avatars. Please retry.")
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"octa/internal/config"
+	"octa/pkg/generator/styles"
+	"octa/pkg/utils"
+)
+
+const (
+	// DefaultGitHubMaxConcurrent bounds outbound GitHub fetches (API call + image download).
+	DefaultGitHubMaxConcurrent = 8
+
+	// DefaultGitHubQueueTimeout is how long a fetch waits for a slot before falling back.
+	DefaultGitHubQueueTimeout = 2 * time.Second
+)
+
+// errGitHubBusy: every GitHub slot stayed taken past image.github_queue_timeout.
+var errGitHubBusy = errors.New("github fetch capacity exhausted")
+
+// githubClient bounds the avatar download so a stalled upstream cannot pin the SingleFlight leader.
+var githubClient = &http.Client{Timeout: 10 * time.Second}
+
+// githubGuard is a semaphore across all usernames. SingleFlight only merges identical
+// usernames, so a flood of distinct ones would otherwise open one upstream call each
+// and burn through GitHub's rate limit.
+var (
+	githubGuard     chan struct{}
+	githubTimeout   time.Duration
+	githubGuardOnce sync.Once
+)
+
+func initGitHubGuard() {
+	limit := config.AppConfig.Image.GitHubMaxConcurrent
+	if limit <= 0 {
+		limit = DefaultGitHubMaxConcurrent
+	}
+	githubGuard = make(chan struct{}, limit)
+
+	timeout, err := time.ParseDuration(config.AppConfig.Image.GitHubQueueTimeout)
+	if err != nil || timeout <= 0 {
+		timeout = DefaultGitHubQueueTimeout
+	}
+	githubTimeout = timeout
+}
+
+// acquireGitHubSlot waits up to image.github_queue_timeout for a fetch slot.
+// Callers must releaseGitHubSlot after a successful acquire.
+func acquireGitHubSlot() bool {
+	githubGuardOnce.Do(initGitHubGuard)
+
+	timer := time.NewTimer(githubTimeout)
+	defer timer.Stop()
+
+	select {
+	case githubGuard <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func releaseGitHubSlot() {
+	<-githubGuard
+}
+
+// serveGitHubBusyFallback answers with the generated avatar for the username while GitHub fetches are saturated.
+// It is neither cached server-side nor by clients, so the real avatar shows up once load drops.
+func serveGitHubBusyFallback(w http.ResponseWriter, r *http.Request, username string) {
+	opts := styles.ResolveOptions(username, nil)
+
+	res, err := generateAvatar(opts)
+	if err != nil {
+		if errors.Is(err, errGenerationBusy) {
+			writeBusy(w)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrImageGenerationFailed, "Failed to generate avatar image.")
+		return
+	}
+
+	markDegraded(w)
+	serveWithETag(w, r, res.Data, res.MimeType)
+}
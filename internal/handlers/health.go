@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"octa/internal/appinfo"
+	"octa/internal/config"
+	"octa/internal/database"
+	"octa/pkg/logger"
+	"octa/pkg/utils"
+)
+
+// readyTimeout bounds the database ping of /ready so a locked database fails the probe
+// instead of hanging it.
+const readyTimeout = 2 * time.Second
+
+// HealthHandler is the liveness probe: the process is up and serving. No database access.
+// GET /health
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status":         "ok",
+		"uptime_seconds": int64(time.Since(appinfo.StartTime).Seconds()),
+	})
+}
+
+// ReadyHandler is the readiness probe: 200 when the write and read database pools answer,
+// 503 otherwise. The cache state is reported but never fails the probe (cache.enabled=false
+// is a valid setup).
+// GET /ready
+func ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	status, code := "ready", http.StatusOK
+	dbStatus := "ok"
+	if err := pingDatabase(ctx); err != nil {
+		logger.LogWarn("Readiness check failed: %v", err)
+		status, code, dbStatus = "unavailable", http.StatusServiceUnavailable, "unreachable"
+	}
+
+	cacheStatus := "disabled"
+	if config.AppConfig.Cache.Enabled {
+		cacheStatus = "enabled"
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, code, map[string]interface{}{
+		"status":   status,
+		"database": dbStatus,
+		"cache":    cacheStatus,
+	})
+}
+
+// pingDatabase runs SELECT 1 on the write pool and, when it is separate, the read pool.
+func pingDatabase(ctx context.Context) error {
+	if database.DB == nil {
+		return errors.New("database not initialized")
+	}
+	if err := database.DB.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+		return err
+	}
+	if database.ReadDB != nil && database.ReadDB != database.DB {
+		return database.ReadDB.WithContext(ctx).Exec("SELECT 1").Error
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"octa/internal/config"
+	"octa/internal/database"
+	"octa/pkg/utils"
+)
+
+// IconSetSizes are the square PNG sizes generated by POST /upload?generate=iconset:
+// favicons (16-48), Android/Windows tiles (64-144), apple-touch-icon (180) and PWA icons (192, 512).
+var IconSetSizes = []int{16, 32, 48, 64, 96, 128, 144, 180, 192, 256, 512}
+
+// IconSetEntry is one generated icon, stored under "<key>-<size>".
+type IconSetEntry struct {
+	Key  string `json:"key"`  // e.g. "app-192"
+	Size int    `json:"size"` // 192
+	URL  string `json:"url"`
+}
+
+// ManifestIcon is an entry of a web app manifest "icons" array.
+type ManifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"` // "192x192"
+	Type  string `json:"type"`  // "image/png"
+}
+
+// uploadIconSet renders the upload at every IconSetSizes size and stores each one as its own
+// asset under a size-suffixed key (keys=app -> app-16, app-32, ...). Re-uploading replaces them.
+// Form fields mode (square/cover/contain), background, padding and metadata apply to every icon;
+// icons are always PNG and never watermarked.
+func uploadIconSet(w http.ResponseWriter, r *http.Request, file io.Reader, keys []string, metadata string, hasMetadata bool) {
+	if len(keys) != 1 {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "An icon set takes exactly one base key (e.g. keys=app).")
+		return
+	}
+	baseKey := keys[0]
+
+	opts, err := iconSetOptions(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrImageProcessingFailed, err.Error())
+		return
+	}
+
+	img, err := decodeUploadImage(file, r)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	// Image Processing (before the DB lock, like single uploads)
+	icons := make([]utils.ProcessedImage, len(IconSetSizes))
+	for i, size := range IconSetSizes {
+		opts.Size = size
+		if icons[i], err = utils.ProcessImage(img, opts); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, utils.ErrImageProcessingFailed, err.Error())
+			return
+		}
+	}
+
+	if err := acquireDBWrite(r.Context()); err != nil {
+		writeWriteBusy(w)
+		return
+	}
+	defer releaseDBWrite()
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to start transaction.")
+		return
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	saved := make([]upsertResult, len(icons))
+	for i, icon := range icons {
+		meta := ImageMeta{Width: icon.Width, Height: icon.Height, Format: icon.Format, Size: int64(len(icon.Data))}
+		var errMsg string
+		if saved[i], errMsg = upsertKeyImage(tx, iconSetKey(baseKey, IconSetSizes[i]), icon.Data, meta, metadata, hasMetadata); errMsg != "" {
+			utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, errMsg)
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Transaction commit failed.")
+		return
+	}
+	committed = true
+
+	baseURL := config.AppConfig.GetBaseUrl()
+	entries := make([]IconSetEntry, len(icons))
+	manifest := make([]ManifestIcon, len(icons))
+	for i, icon := range icons {
+		size := IconSetSizes[i]
+		key := iconSetKey(baseKey, size)
+		updateStatsAndCache(saved[i].Action, saved[i].AssetID, []string{key}, int64(len(icon.Data)), saved[i].OldSize)
+
+		entries[i] = IconSetEntry{Key: key, Size: size, URL: baseURL + "/u/" + key}
+		manifest[i] = ManifestIcon{Src: entries[i].URL, Sizes: fmt.Sprintf("%dx%d", size, size), Type: "image/png"}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "success",
+		"action":   "iconset",
+		"key":      baseKey,
+		"icons":    entries,
+		"manifest": map[string]interface{}{"icons": manifest},
+	})
+}
+
+// iconSetOptions reads the per-icon processing options from the upload form.
+func iconSetOptions(r *http.Request) (utils.ProcessOptions, error) {
+	mode := r.FormValue("mode")
+	switch mode {
+	case "":
+		mode = "square"
+	case "square", "cover", "contain":
+	default:
+		return utils.ProcessOptions{}, errors.New("icon set mode must be square, cover or contain")
+	}
+
+	background, err := uploadBackground(r)
+	if err != nil {
+		return utils.ProcessOptions{}, err
+	}
+
+	return utils.ProcessOptions{
+		Mode:       mode,
+		Quality:    imageQuality(),
+		Format:     "png", // Icons need alpha and lossless edges
+		Background: background,
+		Padding:    utils.ParseInt(r.FormValue("padding"), 0, 0, utils.MaxPaddingPercent),
+	}, nil
+}
+
+func iconSetKey(baseKey string, size int) string {
+	return fmt.Sprintf("%s-%d", baseKey, size)
+}
@@ -7,13 +7,13 @@ import (
 
 var (
 	// Global in-memory cache with 100MB limit
-	globalCache *cache.MemoryCache
+	globalCache cache.Cache
 
 	// SingleFlight group to prevent cache stampedes
 	requestGroup singleflight.Group
 )
 
 
-func SetCache(c *cache.MemoryCache) {
+func SetCache(c cache.Cache) {
     globalCache = c
 }
\ No newline at end of file
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"octa/internal/config"
+	"octa/internal/database"
+	"octa/pkg/cache"
+	"octa/pkg/utils"
+)
+
+// TestMain runs the handlers against a throwaway SQLite file and a memory cache,
+// with the defaults Load applies when no config.yaml is present.
+func TestMain(m *testing.M) {
+	fontPath, err := filepath.Abs("../../fonts/Inter_28pt-SemiBold.ttf")
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir, err := os.MkdirTemp("", "octa-handlers-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatal(err)
+	}
+
+	os.Setenv("AVATAR_DATABASE_PATH", filepath.Join(dir, "test.db"))
+	os.Setenv("AVATAR_SECURITY_UPLOAD_SECRET", "test-secret")
+	os.Setenv("ADMIN_DASHBOARD_USERNAME", "admin")
+	os.Setenv("ADMIN_DASHBOARD_PASSWORD", "admin")
+
+	config.Load()
+	database.InitDB()
+	SetCache(cache.NewMemory())
+	if err := utils.InitFonts(fontPath); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// encodeTestImage returns a small solid image encoded as "png" or "jpeg".
+func encodeTestImage(t testing.TB, format string) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(0, 0, color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff})
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	default:
+		t.Fatalf("unsupported test format %q", format)
+	}
+	if err != nil {
+		t.Fatalf("encode %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+// createTestAsset stores an asset and maps the given keys to it.
+func createTestAsset(t testing.TB, id, format string, keys ...string) []byte {
+	t.Helper()
+	data := encodeTestImage(t, format)
+	img := database.Image{ID: id, Data: data, Width: 8, Height: 8, Format: format, Size: int64(len(data))}
+	if err := database.DB.Create(&img).Error; err != nil {
+		t.Fatalf("create asset %s: %v", id, err)
+	}
+	for _, k := range keys {
+		if err := database.DB.Create(&database.KeyMapping{Key: k, ImageID: id}).Error; err != nil {
+			t.Fatalf("map key %s: %v", k, err)
+		}
+	}
+	t.Cleanup(func() {
+		database.DB.Where("image_id = ?", id).Delete(&database.KeyMapping{})
+		database.DB.Delete(&database.Image{}, "id = ?", id)
+	})
+	return data
+}
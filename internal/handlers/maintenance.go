@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"octa/internal/database"
+	"octa/pkg/logger"
+	"octa/pkg/utils"
+)
+
+const (
+	// VacuumTimeout bounds a manual VACUUM. It keeps running after the request returns.
+	VacuumTimeout = 30 * time.Minute
+
+	// VacuumWait is how long POST /console/api/db/vacuum waits for the result before
+	// answering 202. It stays below the server's 30s WriteTimeout.
+	VacuumWait = 20 * time.Second
+
+	// CheckpointTimeout bounds a manual WAL checkpoint.
+	CheckpointTimeout = 20 * time.Second
+)
+
+// StorageSizes are the on-disk sizes of the database file and its WAL.
+type StorageSizes struct {
+	FileSize     int64 `json:"file_size"`
+	WALSize      int64 `json:"wal_size"`
+	PhysicalSize int64 `json:"physical_size"`
+}
+
+// VacuumStatus is the last manual VACUUM. Kept in memory only.
+type VacuumStatus struct {
+	State      string        `json:"state"` // "idle", "running", "done", "failed"
+	Before     *StorageSizes `json:"before,omitempty"`
+	After      *StorageSizes `json:"after,omitempty"`
+	Reclaimed  int64         `json:"reclaimed"` // Bytes given back to the disk
+	Error      string        `json:"error,omitempty"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+var vacuumJob = struct {
+	sync.Mutex
+	status VacuumStatus
+}{status: VacuumStatus{State: "idle"}}
+
+// storageSizes stats the database files; a failed stat reports zeros.
+func storageSizes() *StorageSizes {
+	fileSize, walSize, err := database.FileSizes()
+	if err != nil {
+		logger.LogWarn("Failed to stat database files: %v", err)
+	}
+	return &StorageSizes{FileSize: fileSize, WALSize: walSize, PhysicalSize: fileSize + walSize}
+}
+
+// VacuumDB checkpoints the WAL and rebuilds the database file on demand (e.g. after a big
+// bulk delete), instead of waiting for the storage cleaner to find it bloated.
+// The VACUUM runs in the background: the response carries the before/after sizes when it
+// finishes within VacuumWait, otherwise 202 and GET /console/api/db/vacuum reports the result.
+// POST /console/api/db/vacuum
+func VacuumDB(w http.ResponseWriter, r *http.Request) {
+	// Shared with backups and the cleaner: two whole-database rewrites must never overlap.
+	if !database.MaintenanceMu.TryLock() {
+		utils.WriteError(w, http.StatusConflict, utils.ErrResourceConflict, "Another backup or database maintenance is currently in progress.")
+		return
+	}
+
+	now := time.Now()
+	vacuumJob.Lock()
+	vacuumJob.status = VacuumStatus{State: "running", Before: storageSizes(), StartedAt: &now}
+	vacuumJob.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer database.MaintenanceMu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), VacuumTimeout)
+		defer cancel()
+
+		logger.LogInfo("Manual VACUUM started from the console")
+		err := database.Vacuum(ctx)
+		after := storageSizes()
+		finished := time.Now()
+
+		vacuumJob.Lock()
+		defer vacuumJob.Unlock()
+		st := &vacuumJob.status
+		st.After, st.FinishedAt = after, &finished
+		if err != nil {
+			st.State, st.Error = "failed", err.Error()
+			logger.LogError("Manual VACUUM failed: %v", err)
+			return
+		}
+		st.State = "done"
+		st.Reclaimed = st.Before.PhysicalSize - after.PhysicalSize
+		logger.LogInfo("Manual VACUUM completed in %v. Reclaimed %s.", finished.Sub(now).Round(time.Millisecond), utils.FormatBytes(st.Reclaimed))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(VacuumWait):
+	case <-r.Context().Done():
+		return
+	}
+
+	vacuumJob.Lock()
+	status := vacuumJob.status
+	vacuumJob.Unlock()
+
+	switch status.State {
+	case "running":
+		utils.WriteJSON(w, http.StatusAccepted, status)
+	case "failed":
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "VACUUM failed: "+status.Error)
+	default:
+		utils.WriteJSON(w, http.StatusOK, status)
+	}
+}
+
+// GetVacuumStatus reports the last manual VACUUM (state "idle" before the first one).
+// GET /console/api/db/vacuum
+func GetVacuumStatus(w http.ResponseWriter, r *http.Request) {
+	vacuumJob.Lock()
+	status := vacuumJob.status
+	vacuumJob.Unlock()
+
+	utils.WriteJSON(w, http.StatusOK, status)
+}
+
+// CheckpointDB copies the WAL into the database file and truncates it. It is quick compared
+// to VACUUM, so it runs within the request. "busy": true means active readers kept part of
+// the WAL; running it again later finishes the job.
+// POST /console/api/db/checkpoint
+func CheckpointDB(w http.ResponseWriter, r *http.Request) {
+	if !database.MaintenanceMu.TryLock() {
+		utils.WriteError(w, http.StatusConflict, utils.ErrResourceConflict, "Another backup or database maintenance is currently in progress.")
+		return
+	}
+	defer database.MaintenanceMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), CheckpointTimeout)
+	defer cancel()
+
+	before := storageSizes()
+	res, err := database.Checkpoint(ctx)
+	if err != nil {
+		logger.LogError("Manual WAL checkpoint failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "WAL checkpoint failed.")
+		return
+	}
+	after := storageSizes()
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     "done",
+		"checkpoint": res,
+		"before":     before,
+		"after":      after,
+		"reclaimed":  before.PhysicalSize - after.PhysicalSize,
+	})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MaxMetadataTags        = 20  // Tags per asset
+	MaxMetadataKeyLength   = 64  // e.g. "category"
+	MaxMetadataValueLength = 256 // e.g. "logos"
+
+	// tagQueryPrefix marks metadata filters in list queries: ?tag.category=logos
+	tagQueryPrefix = "tag."
+)
+
+// parseMetadata validates the optional 'metadata' upload field: a flat JSON object of strings.
+// ok=false means the field was absent and the stored metadata must be left untouched.
+func parseMetadata(raw string) (stored string, ok bool, err error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false, nil
+	}
+
+	var tags map[string]string
+	if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+		return "", false, errors.New("metadata must be a JSON object of string values")
+	}
+
+	if len(tags) > MaxMetadataTags {
+		return "", false, fmt.Errorf("metadata can have at most %d tags", MaxMetadataTags)
+	}
+	for k, v := range tags {
+		if !isValidTagKey(k) {
+			return "", false, fmt.Errorf("invalid metadata tag '%s': use letters, digits, '_', '-' or '.' (max %d chars)", k, MaxMetadataKeyLength)
+		}
+		if len(v) > MaxMetadataValueLength {
+			return "", false, fmt.Errorf("metadata tag '%s' exceeds %d characters", k, MaxMetadataValueLength)
+		}
+	}
+
+	if len(tags) == 0 {
+		return "", true, nil // "{}" clears the metadata
+	}
+
+	b, _ := json.Marshal(tags) // Sorted keys, canonical form
+	return string(b), true, nil
+}
+
+// decodeMetadata turns the stored JSON text back into tags. Empty or corrupt values yield nil.
+func decodeMetadata(stored string) map[string]string {
+	if stored == "" {
+		return nil
+	}
+	var tags map[string]string
+	if err := json.Unmarshal([]byte(stored), &tags); err != nil {
+		return nil
+	}
+	return tags
+}
+
+// tagFilters extracts ?tag.<name>=<value> filters. Invalid tag names are ignored.
+func tagFilters(query url.Values) map[string]string {
+	filters := make(map[string]string)
+	for k := range query {
+		name, found := strings.CutPrefix(k, tagQueryPrefix)
+		if !found || !isValidTagKey(name) {
+			continue
+		}
+		filters[name] = query.Get(k)
+	}
+	return filters
+}
+
+// applyTagFilters narrows q to assets whose metadata column matches every filter.
+// Rows without (valid) metadata never match; json_valid guards json_extract against empty strings.
+func applyTagFilters(q *gorm.DB, column string, filters map[string]string) *gorm.DB {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Stable SQL for the statement cache
+
+	for _, name := range names {
+		expr := fmt.Sprintf("json_extract(CASE WHEN json_valid(%[1]s) THEN %[1]s END, ?) = ?", column)
+		q = q.Where(expr, `$."`+name+`"`, filters[name])
+	}
+	return q
+}
+
+// isValidTagKey keeps tag names safe to embed in a JSON path.
+func isValidTagKey(k string) bool {
+	if k == "" || len(k) > MaxMetadataKeyLength {
+		return false
+	}
+	for _, c := range k {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
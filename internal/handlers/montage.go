@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"octa/pkg/generator/styles"
+	"octa/pkg/utils"
+)
+
+const (
+	MaxMontageSeeds     = 25   // Avatars per montage
+	DefaultMontageCell  = 128  // Cell size in px when ?size is not given
+	MaxMontageDimension = 2048 // Longest side of the composited image in px
+)
+
+// ServeMontage composites the avatars of several seeds into one PNG grid.
+// Every style param (theme, rounded, bg, ...) applies to all cells; ?size is the cell size.
+// Path: /montage?seeds=a,b,c&cols=3
+func ServeMontage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	seeds := make([]string, 0, MaxMontageSeeds)
+	for _, s := range strings.Split(query.Get("seeds"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			seeds = append(seeds, s)
+		}
+	}
+	if len(seeds) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestMissingKey, "Parameter 'seeds' is required (e.g. seeds=a,b,c).")
+		return
+	}
+	if len(seeds) > MaxMontageSeeds {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Too many seeds: a montage can have at most "+strconv.Itoa(MaxMontageSeeds)+".")
+		return
+	}
+
+	// Layout
+	cols := utils.ParseInt(query.Get("cols"), int(math.Ceil(math.Sqrt(float64(len(seeds))))), 1, len(seeds))
+	rows := (len(seeds) + cols - 1) / cols
+
+	cell := DefaultMontageCell
+	if query.Get("size") != "" || query.Get("w") != "" {
+		cell = styles.ResolveOptions("", query).Size
+	}
+	if longest := max(cols, rows); cell*longest > MaxMontageDimension {
+		cell = MaxMontageDimension / longest
+	}
+
+	// Cells are always PNG so they can be decoded and composited.
+	cellQuery := url.Values{}
+	for k, v := range query {
+		cellQuery[k] = v
+	}
+	cellQuery.Set("format", "png")
+	cellQuery.Del("type")
+	cellQuery.Set("size", strconv.Itoa(cell))
+
+	// Key: layout + every cell's resolved options. Seed order defines grid positions, so it is kept as given.
+	cellOpts := make([]styles.Options, len(seeds))
+	h := sha256.New()
+	fmt.Fprintf(h, "cols=%d", cols)
+	for i, seed := range seeds {
+		cellOpts[i] = styles.ResolveOptions(seed, cellQuery)
+		fmt.Fprintf(h, "|%s=%s", seed, cellOpts[i].Key())
+	}
+	uniqueKey := "montage:" + hex.EncodeToString(h.Sum(nil)[:16])
+	cell = cellOpts[0].Size // image.size_step may have snapped it
+
+	// Same DoS rule as single avatars: custom colors are not cached.
+	bg := query.Get("bg")
+	shouldCache := (bg == "" || styles.TransparentBackground(bg)) && query.Get("color") == "" && query.Get("ringColor") == ""
+
+	data, err := doShared(r.Context(), uniqueKey, func() (interface{}, error) {
+		if shouldCache {
+			if cached, ok := globalCache.Get(uniqueKey); ok {
+				return cached, nil
+			}
+		}
+
+		canvas := image.NewRGBA(image.Rect(0, 0, cols*cell, rows*cell))
+		for i, opts := range cellOpts {
+			cellBytes, err := renderMontageCell(seeds[i], opts, shouldCache)
+			if err != nil {
+				return nil, err
+			}
+			img, err := png.Decode(bytes.NewReader(cellBytes))
+			if err != nil {
+				return nil, err
+			}
+			at := image.Pt((i%cols)*cell, (i/cols)*cell)
+			draw.Draw(canvas, image.Rectangle{Min: at, Max: at.Add(image.Pt(cell, cell))}, img, image.Point{}, draw.Src)
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, canvas); err != nil {
+			return nil, err
+		}
+
+		if shouldCache {
+			cacheGenerated(uniqueKey, buf.Bytes())
+		}
+		return buf.Bytes(), nil
+	})
+
+	if err != nil {
+		if errors.Is(err, errGenerationBusy) {
+			writeBusy(w)
+			return
+		}
+		if writeSharedTimeout(w, err) {
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrImageGenerationFailed, "Failed to generate montage.")
+		return
+	}
+
+	markImmutable(w)
+	serveWithETag(w, r, data.([]byte), "image/png")
+}
+
+// renderMontageCell reuses (and fills) the single-avatar cache so montages and /avatar/{seed} share work.
+// Degraded renders are rejected: a montage must not bake a low-res placeholder into a cached grid.
+func renderMontageCell(seed string, opts styles.Options, cacheable bool) ([]byte, error) {
+	cellKey := cacheKeyPrefix("gen", seed) + opts.Key()
+	if cacheable {
+		if cached, ok := globalCache.Get(cellKey); ok {
+			return cached, nil
+		}
+	}
+
+	res, err := generateAvatar(opts)
+	if err != nil {
+		return nil, err
+	}
+	if res.Degraded {
+		return nil, errGenerationBusy
+	}
+
+	if cacheable {
+		cacheGenerated(cellKey, res.Data)
+	}
+	return res.Data, nil
+}
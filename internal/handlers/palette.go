@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"net/http"
+
+	"octa/pkg/generator/styles"
+	"octa/pkg/utils"
+)
+
+// AvatarPalette is the resolved color identity of a seed, without the image.
+type AvatarPalette struct {
+	Seed       string   `json:"seed"`
+	Style      string   `json:"style"`      // "color", "gradient" or "soft"
+	Background []string `json:"background"` // One hex per stop (two for gradients)
+	Text       string   `json:"text"`
+}
+
+// ServeAvatarAction dispatches sub-resources of a generated avatar.
+// Path: /avatar/:seed/:action
+func ServeAvatarAction(w http.ResponseWriter, r *http.Request) {
+	switch r.PathValue("action") {
+	case "palette":
+		ServeAvatarPalette(w, r)
+	case "srcset":
+		ServeAvatarSrcset(w, r)
+	default:
+		utils.WriteError(w, http.StatusNotFound, utils.ErrResourceNotFound, "Unknown avatar resource.")
+	}
+}
+
+// ServeAvatarPalette returns the colors /avatar/:seed would render with the same params,
+// so UIs can theme around an avatar without downloading it.
+// Path: /avatar/:seed/palette?theme=gradient/pro
+func ServeAvatarPalette(w http.ResponseWriter, r *http.Request) {
+	seed := r.PathValue("seed")
+	if seed == "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestMissingKey, "Avatar seed key is missing.")
+		return
+	}
+
+	opts := styles.ResolveOptions(seed, r.URL.Query())
+
+	palette := AvatarPalette{
+		Seed:       seed,
+		Style:      opts.Style,
+		Background: []string{hexColor(opts.BG1)},
+		Text:       hexColor(opts.Text),
+	}
+	if opts.BG2 != opts.BG1 {
+		palette.Background = append(palette.Background, hexColor(opts.BG2))
+	}
+
+	data, err := json.Marshal(palette)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to encode palette.")
+		return
+	}
+
+	// Deterministic and tiny: no server cache, HTTP caching is enough.
+	markImmutable(w)
+	serveWithETag(w, r, data, "application/json")
+}
+
+// hexColor formats a color as #rrggbb, or #rrggbbaa when it is translucent.
+func hexColor(c color.RGBA) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A < 255 {
+		return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"time"
+
+	"octa/internal/database"
+	"octa/pkg/cache"
+	"octa/pkg/logger"
+)
+
+// preloadKeyBatch keeps the key lookup's IN (...) list under SQLite's bound-parameter limit.
+const preloadKeyBatch = 500
+
+// PreloadCache warms the cache with the n most recently updated assets (img:<id>) and the
+// keys pointing to them (map:<key>), so the first /u/:key requests after a deploy don't all
+// hit the database. Assets over cache.MaxItemSize are skipped, as Set would drop them anyway.
+// Meant to run in the background at startup; n <= 0 disables it.
+func PreloadCache(n int) {
+	if n <= 0 || globalCache == nil || database.ReadDB == nil {
+		return
+	}
+	start := time.Now()
+
+	// Stream the rows: n blobs are never held in memory at once.
+	rows, err := database.ReadDB.Model(&database.Image{}).
+		Select("id", "data").
+		Where("LENGTH(data) <= ?", cache.MaxItemSize).
+		Order("updated_at DESC").
+		Limit(n).
+		Rows()
+	if err != nil {
+		logger.LogWarn("Cache preload skipped: %v", err)
+		return
+	}
+
+	ids := make([]string, 0, n)
+	for rows.Next() {
+		var id string
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			logger.LogWarn("Cache preload stopped early: %v", err)
+			break
+		}
+		globalCache.Set("img:"+id, data)
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	mapped := 0
+	for i := 0; i < len(ids); i += preloadKeyBatch {
+		batch := ids[i:min(i+preloadKeyBatch, len(ids))]
+
+		var mappings []database.KeyMapping
+		if err := database.ReadDB.Select("key", "image_id").Where("image_id IN ?", batch).Find(&mappings).Error; err != nil {
+			logger.LogWarn("Cache preload of keys failed: %v", err)
+			break
+		}
+		for _, m := range mappings {
+			globalCache.Set("map:"+m.Key, []byte(m.ImageID))
+		}
+		mapped += len(mappings)
+	}
+
+	logger.LogInfo("Cache preloaded: %d assets, %d keys in %s", len(ids), mapped, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"time"
+
+	"octa/internal/database"
+	"octa/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// ReprocessRequest overrides how a stored image is re-encoded. Zero values keep the current
+// dimensions and format and use the configured image.quality.
+type ReprocessRequest struct {
+	Mode       string `json:"mode"`       // "fit" (default), "square", "cover", "contain" or "scale"
+	Size       int    `json:"size"`       // Target px for fit/square (default: current longest side)
+	Scale      int    `json:"scale"`      // Percentage for "scale" (1-100)
+	Quality    int    `json:"quality"`    // 1-100 (default: image.quality)
+	Format     string `json:"format"`     // "jpeg", "png" or "webp" (default: current format)
+	Watermark  bool   `json:"watermark"`  // Off by default: the stored image may already carry one
+	Background string `json:"background"` // Letterbox fill for "contain" (hex, rgba(), CSS name; default transparent)
+}
+
+// errInvalidReprocess marks client errors in a ReprocessRequest.
+var errInvalidReprocess = errors.New("invalid reprocess options")
+
+// errAssetChanged means the asset was replaced or deleted while it was being re-encoded.
+// The new image is discarded instead of overwriting the newer one.
+var errAssetChanged = errors.New("asset changed while reprocessing")
+
+// ReprocessAsset re-runs the upload pipeline on a stored image, e.g. after changing image.quality
+// or to convert it to another format. Keys and metadata are untouched.
+// POST /console/api/assets/{id}/reprocess
+func ReprocessAsset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Asset ID is required.")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 2048)
+
+	var req ReprocessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Invalid JSON body.")
+		return
+	}
+
+	meta, err := reprocessImage(r.Context(), id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrAssetNotFound):
+			utils.WriteError(w, http.StatusNotFound, utils.ErrResourceNotFound, "Asset not found.")
+		case errors.Is(err, errInvalidReprocess):
+			utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, err.Error())
+		case errors.Is(err, errAssetChanged):
+			utils.WriteError(w, http.StatusConflict, utils.ErrResourceConflict, "Asset changed while it was being reprocessed. Try again.")
+		case errors.Is(err, errWriteBusy):
+			writeWriteBusy(w)
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, utils.ErrImageProcessingFailed, "Failed to reprocess image.")
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"action": "reprocessed",
+		"id":     id,
+		"format": meta.Format,
+		"width":  meta.Width,
+		"height": meta.Height,
+		"size":   meta.Size,
+	})
+}
+
+// reprocessImage decodes the stored blob of one asset, processes it with req and writes it back.
+// Decoding and encoding run outside the dbGuard; only the row update is serialized.
+// The update only applies if updated_at is unchanged, so an upload that lands meanwhile is not overwritten.
+func reprocessImage(ctx context.Context, id string, req ReprocessRequest) (ImageMeta, error) {
+	var stored database.Image
+	err := database.DB.WithContext(ctx).
+		Select("id, data, format, width, height, size, updated_at").
+		Where("id = ?", id).
+		Take(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ImageMeta{}, utils.ErrAssetNotFound
+	}
+	if err != nil {
+		return ImageMeta{}, err
+	}
+
+	opts, err := resolveReprocessOptions(req, stored)
+	if err != nil {
+		return ImageMeta{}, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(stored.Data))
+	if err != nil {
+		return ImageMeta{}, fmt.Errorf("decode stored image: %w", err)
+	}
+
+	out, err := utils.ProcessImage(img, opts)
+	if err != nil {
+		return ImageMeta{}, err
+	}
+	meta := ImageMeta{Width: out.Width, Height: out.Height, Format: out.Format, Size: int64(len(out.Data))}
+
+	if err := acquireDBWrite(ctx); err != nil {
+		return ImageMeta{}, err
+	}
+	defer releaseDBWrite()
+
+	res := database.DB.WithContext(ctx).Model(&database.Image{}).Where("id = ? AND updated_at = ?", id, stored.UpdatedAt).Updates(database.Image{
+		Data: out.Data, Width: out.Width, Height: out.Height, Format: meta.Format, Size: meta.Size,
+		UpdatedAt: time.Now(),
+	})
+	if res.Error != nil {
+		return ImageMeta{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ImageMeta{}, errAssetChanged // Replaced or deleted while we were encoding
+	}
+
+	// Same bookkeeping as an upload replacing the image; keys did not change.
+	updateStatsAndCache("updated", id, nil, meta.Size, stored.Size)
+
+	return meta, nil
+}
+
+// resolveReprocessOptions fills defaults from the stored image and validates the request.
+func resolveReprocessOptions(req ReprocessRequest, stored database.Image) (utils.ProcessOptions, error) {
+	opts := utils.ProcessOptions{
+		Mode:      req.Mode,
+		Size:      req.Size,
+		Scale:     req.Scale,
+		Quality:   req.Quality,
+		Format:    req.Format,
+		Watermark: req.Watermark && utils.WatermarkLoaded(),
+	}
+
+	if opts.Mode == "" {
+		opts.Mode = "fit"
+	}
+	switch opts.Mode {
+	case "fit", "square", "cover", "contain", "scale":
+	default:
+		return opts, fmt.Errorf("%w: mode must be fit, square, cover, contain or scale", errInvalidReprocess)
+	}
+
+	if req.Background != "" {
+		c, err := utils.ParseColor(req.Background)
+		if err != nil {
+			return opts, fmt.Errorf("%w: invalid background color '%s'", errInvalidReprocess, req.Background)
+		}
+		opts.Background = c
+	}
+
+	if opts.Size == 0 {
+		opts.Size = max(stored.Width, stored.Height)
+	}
+	if opts.Size < 16 || opts.Size > 2048 {
+		return opts, fmt.Errorf("%w: size must be between 16 and 2048", errInvalidReprocess)
+	}
+
+	if opts.Mode == "scale" && (opts.Scale < 1 || opts.Scale > 100) {
+		return opts, fmt.Errorf("%w: scale must be between 1 and 100", errInvalidReprocess)
+	}
+
+	if opts.Quality == 0 {
+		opts.Quality = imageQuality()
+	}
+	if opts.Quality < 1 || opts.Quality > 100 {
+		return opts, fmt.Errorf("%w: quality must be between 1 and 100", errInvalidReprocess)
+	}
+
+	if opts.Format == "" {
+		opts.Format = stored.Format
+		if !utils.ProcessFormats[opts.Format] {
+			opts.Format = "jpeg" // e.g. a GIF stored in original mode
+		}
+	}
+	if !utils.ProcessFormats[opts.Format] {
+		return opts, fmt.Errorf("%w: format must be jpeg, png or webp", errInvalidReprocess)
+	}
+
+	return opts, nil
+}
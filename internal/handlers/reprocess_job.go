@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"octa/internal/database"
+	"octa/pkg/logger"
+	"octa/pkg/utils"
+)
+
+const (
+	ReprocessBatchSize  = 50                     // Images loaded per batch
+	ReprocessBatchPause = 250 * time.Millisecond // Breather between batches so uploads get the writer lock
+)
+
+// ReprocessJobStatus is the progress of the bulk reprocess job. Kept in memory only.
+type ReprocessJobStatus struct {
+	State      string     `json:"state"` // "idle", "running", "done", "canceled"
+	Format     string     `json:"format,omitempty"`
+	Total      int64      `json:"total"`
+	Processed  int64      `json:"processed"` // Converted successfully
+	Skipped    int64      `json:"skipped"`   // Already in the target format, or changed while being converted
+	Failed     int64      `json:"failed"`
+	LastError  string     `json:"last_error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+var reprocessJob = struct {
+	sync.Mutex
+	status ReprocessJobStatus
+	cancel context.CancelFunc
+}{status: ReprocessJobStatus{State: "idle"}}
+
+// StartReprocessJob re-encodes every stored image to the given format in the background.
+// Images already in that format are skipped. Only one job runs at a time.
+// POST /console/api/jobs/reprocess  {"format":"webp","quality":80}
+func StartReprocessJob(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 2048)
+
+	var req ReprocessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Invalid JSON body.")
+		return
+	}
+	if !utils.ProcessFormats[req.Format] {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "'format' is required: jpeg, png or webp.")
+		return
+	}
+
+	// Validate the rest once up front instead of failing every image the same way.
+	if _, err := resolveReprocessOptions(req, database.Image{Width: 256, Height: 256}); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, err.Error())
+		return
+	}
+
+	reprocessJob.Lock()
+	if reprocessJob.status.State == "running" {
+		status := reprocessJob.status
+		reprocessJob.Unlock()
+		utils.WriteJSON(w, http.StatusConflict, map[string]interface{}{
+			"code":    utils.ErrResourceConflict,
+			"message": "A reprocess job is already running.",
+			"status":  http.StatusConflict,
+			"job":     status,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	reprocessJob.status = ReprocessJobStatus{State: "running", Format: req.Format, StartedAt: &now}
+	reprocessJob.cancel = cancel
+	status := reprocessJob.status
+	reprocessJob.Unlock()
+
+	go runReprocessJob(ctx, req)
+
+	utils.WriteJSON(w, http.StatusAccepted, status)
+}
+
+// GetReprocessJob reports the progress of the current (or last) job.
+// GET /console/api/jobs/reprocess
+func GetReprocessJob(w http.ResponseWriter, r *http.Request) {
+	reprocessJob.Lock()
+	status := reprocessJob.status
+	reprocessJob.Unlock()
+
+	utils.WriteJSON(w, http.StatusOK, status)
+}
+
+// CancelReprocessJob stops the running job after the image in progress. Converted images stay converted.
+// DELETE /console/api/jobs/reprocess
+func CancelReprocessJob(w http.ResponseWriter, r *http.Request) {
+	reprocessJob.Lock()
+	defer reprocessJob.Unlock()
+
+	if reprocessJob.status.State != "running" {
+		utils.WriteError(w, http.StatusNotFound, utils.ErrResourceNotFound, "No reprocess job is running.")
+		return
+	}
+	reprocessJob.cancel()
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"status": "success",
+		"action": "canceling",
+	})
+}
+
+// runReprocessJob walks images by ID (keyset pagination, stable while rows change)
+// and reprocesses them one by one. Each write takes the dbGuard like an upload does.
+func runReprocessJob(ctx context.Context, req ReprocessRequest) {
+	logger.LogInfo("Reprocess job started: converting images to %s", req.Format)
+
+	var total int64
+	if err := database.DB.WithContext(ctx).Model(&database.Image{}).Count(&total).Error; err == nil {
+		updateReprocessJob(func(s *ReprocessJobStatus) { s.Total = total })
+	}
+
+	lastID := ""
+batches:
+	for {
+		var batch []struct {
+			ID     string
+			Format string
+		}
+		err := database.DB.WithContext(ctx).
+			Model(&database.Image{}).
+			Select("id, format").
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(ReprocessBatchSize).
+			Scan(&batch).Error
+		if err != nil {
+			if ctx.Err() == nil {
+				updateReprocessJob(func(s *ReprocessJobStatus) { s.LastError = err.Error() })
+			}
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, img := range batch {
+			if ctx.Err() != nil {
+				break batches
+			}
+			if img.Format == req.Format {
+				updateReprocessJob(func(s *ReprocessJobStatus) { s.Skipped++ })
+				continue
+			}
+
+			if err := reprocessWithRetry(ctx, img.ID, req); err != nil {
+				if ctx.Err() != nil {
+					break batches
+				}
+				if errors.Is(err, errAssetChanged) {
+					// A newer upload or a delete won; nothing left to convert here
+					updateReprocessJob(func(s *ReprocessJobStatus) { s.Skipped++ })
+					continue
+				}
+				logger.LogWarn("Reprocess failed for asset %s: %v", img.ID, err)
+				updateReprocessJob(func(s *ReprocessJobStatus) {
+					s.Failed++
+					s.LastError = img.ID + ": " + err.Error()
+				})
+				continue
+			}
+			updateReprocessJob(func(s *ReprocessJobStatus) { s.Processed++ })
+		}
+		lastID = batch[len(batch)-1].ID
+
+		select {
+		case <-ctx.Done():
+			break batches
+		case <-time.After(ReprocessBatchPause):
+		}
+	}
+
+	canceled := ctx.Err() != nil
+	updateReprocessJob(func(s *ReprocessJobStatus) {
+		now := time.Now()
+		s.FinishedAt = &now
+		s.State = "done"
+		if canceled {
+			s.State = "canceled"
+		}
+	})
+
+	reprocessJob.Lock()
+	status := reprocessJob.status
+	reprocessJob.cancel() // Release the context
+	reprocessJob.Unlock()
+
+	logger.LogInfo("Reprocess job %s: %d converted, %d skipped, %d failed",
+		status.State, status.Processed, status.Skipped, status.Failed)
+}
+
+func updateReprocessJob(fn func(s *ReprocessJobStatus)) {
+	reprocessJob.Lock()
+	fn(&reprocessJob.status)
+	reprocessJob.Unlock()
+}
+
+// reprocessWithRetry yields to uploads: a saturated write queue delays the job instead of failing the asset.
+func reprocessWithRetry(ctx context.Context, id string, req ReprocessRequest) error {
+	for {
+		_, err := reprocessImage(ctx, id, req)
+		if !errors.Is(err, errWriteBusy) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(ReprocessBatchPause):
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"octa/internal/database"
+	"octa/pkg/utils"
+)
+
+// The write is guarded by updated_at; an untouched asset must still match it and get converted.
+func TestReprocessImage(t *testing.T) {
+	createTestAsset(t, "asset-reprocess", "png")
+
+	meta, err := reprocessImage(context.Background(), "asset-reprocess", ReprocessRequest{Format: "jpeg", Size: 16})
+	if err != nil {
+		t.Fatalf("reprocess: %v", err)
+	}
+	if meta.Format != "jpeg" {
+		t.Errorf("format = %q, want jpeg", meta.Format)
+	}
+
+	var stored database.Image
+	database.DB.Select("format").Where("id = ?", "asset-reprocess").Take(&stored)
+	if stored.Format != "jpeg" {
+		t.Errorf("stored format = %q, want jpeg", stored.Format)
+	}
+
+	if _, err := reprocessImage(context.Background(), "no-such-asset", ReprocessRequest{}); !errors.Is(err, utils.ErrAssetNotFound) {
+		t.Errorf("unknown asset: err = %v, want ErrAssetNotFound", err)
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"octa/internal/config"
+	"octa/pkg/logger"
+	"octa/pkg/utils"
+)
+
+// MaxSettingsBodySize caps an imported settings document (it is a few hundred bytes).
+const MaxSettingsBodySize = 64 * 1024
+
+// settingsMu serializes imports so two admins can't interleave file writes.
+var settingsMu sync.Mutex
+
+// ExportSettings downloads the portable settings (rate limits, cache, CORS, image defaults)
+// as JSON. Secrets are not part of config.Settings, so nothing needs redacting.
+// GET /console/api/settings/export
+func ExportSettings(w http.ResponseWriter, r *http.Request) {
+	filename := fmt.Sprintf("octa_settings_%s.json", time.Now().Format("2006-01-02_15-04-05"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSON(w, http.StatusOK, config.AppConfig.ExportSettings())
+}
+
+// ImportSettings validates an exported settings document and saves it to
+// consoleui.settings_file. Fields left out keep the value already saved in that file, or the
+// running value if nothing was saved yet. The server has no hot reload, so saved settings
+// apply on the next restart (the response says so).
+// POST /console/api/settings/import
+func ImportSettings(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxSettingsBodySize)
+
+	path := config.AppConfig.ConsoleUI.SettingsFile
+	if path == "" {
+		utils.WriteError(w, http.StatusConflict, utils.ErrRequestInvalid, "Settings import is disabled (consoleui.settings_file is empty).")
+		return
+	}
+
+	// Held from reading the saved file to writing it, so a concurrent import can't be lost
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	// Start from the saved values so partial documents are merges, not resets. Merging over the
+	// running config instead would drop earlier imports that are still waiting for a restart.
+	settings, err := config.LoadSettings(path, config.AppConfig.ExportSettings())
+	if err != nil {
+		logger.LogWarn("Settings file %s unreadable, merging over the running config: %v", path, err)
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&settings); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Invalid settings JSON: "+err.Error())
+		return
+	}
+	if settings.Version != config.SettingsVersion {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid,
+			fmt.Sprintf("Unsupported settings version %d (expected %d).", settings.Version, config.SettingsVersion))
+		return
+	}
+
+	candidate := config.AppConfig.WithSettings(settings)
+	if err := candidate.Validate(); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrValidationInvalidFormat, err.Error())
+		return
+	}
+
+	if err := config.SaveSettings(path, settings); err != nil {
+		logger.LogError("Settings import failed: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to save settings.")
+		return
+	}
+
+	logger.LogInfo("Settings imported from the console and saved to %s", path)
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status":           "saved",
+		"path":             path,
+		"restart_required": true,
+		"settings":         settings,
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"octa/internal/config"
+	"octa/pkg/generator/styles"
+	"octa/pkg/utils"
+)
+
+// MaxSrcsetSizes bounds the candidates of one srcset.
+const MaxSrcsetSizes = 10
+
+// DefaultSrcsetSizes is used when ?sizes is not given: 1x, 2x and 4x of a 64px avatar.
+var DefaultSrcsetSizes = []int{64, 128, 256}
+
+// SrcsetSource is one candidate image of a srcset.
+type SrcsetSource struct {
+	Size int    `json:"size"` // Rendered size in px, after image.size_step snapping
+	URL  string `json:"url"`
+}
+
+// AvatarSrcset is ready to drop into <img src="..." srcset="...">.
+type AvatarSrcset struct {
+	Seed    string         `json:"seed"`
+	Src     string         `json:"src"`    // Smallest candidate, for browsers without srcset
+	Srcset  string         `json:"srcset"` // "url 64w, url 128w, ..."
+	Sources []SrcsetSource `json:"sources"`
+}
+
+// ServeAvatarSrcset builds /avatar/:seed URLs for several sizes. Every other param
+// (theme, rounded, format, ...) is carried over, so all candidates show the same avatar.
+// Path: /avatar/:seed/srcset?sizes=64,128,256
+func ServeAvatarSrcset(w http.ResponseWriter, r *http.Request) {
+	seed := r.PathValue("seed")
+	if seed == "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestMissingKey, "Avatar seed key is missing.")
+		return
+	}
+
+	query := r.URL.Query()
+
+	sizes := DefaultSrcsetSizes
+	if raw := strings.TrimSpace(query.Get("sizes")); raw != "" {
+		parts := strings.Split(raw, ",")
+		if len(parts) > MaxSrcsetSizes {
+			utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Too many sizes: a srcset can have at most "+strconv.Itoa(MaxSrcsetSizes)+".")
+			return
+		}
+		sizes = make([]int, 0, len(parts))
+		for _, p := range parts {
+			sz, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || sz <= 0 {
+				utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Invalid size '"+strings.TrimSpace(p)+"': sizes must be positive integers.")
+				return
+			}
+			sizes = append(sizes, sz)
+		}
+	}
+
+	params := url.Values{}
+	for k, v := range query {
+		params[k] = v
+	}
+	params.Del("sizes")
+	params.Del("w")
+
+	// Sizes are snapped like the renderer does, so two requested sizes may collapse into one candidate.
+	snapped := make([]int, len(sizes))
+	for i, sz := range sizes {
+		snapped[i] = styles.SnapSize(sz)
+	}
+	sort.Ints(snapped)
+	snapped = slices.Compact(snapped)
+
+	base := config.AppConfig.GetBaseUrl() + "/avatar/" + url.PathEscape(seed)
+	resp := AvatarSrcset{Seed: seed, Sources: make([]SrcsetSource, 0, len(snapped))}
+	candidates := make([]string, 0, len(snapped))
+	for _, sz := range snapped {
+		params.Set("size", strconv.Itoa(sz))
+		src := base + "?" + params.Encode()
+		resp.Sources = append(resp.Sources, SrcsetSource{Size: sz, URL: src})
+		candidates = append(candidates, src+" "+strconv.Itoa(sz)+"w")
+	}
+	resp.Src = resp.Sources[0].URL
+	resp.Srcset = strings.Join(candidates, ", ")
+
+	// URLs stay readable: no \u0026 for '&'.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(resp); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to encode srcset.")
+		return
+	}
+
+	serveWithETag(w, r, buf.Bytes(), "application/json")
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"octa/internal/appinfo"
+	"octa/internal/config"
+	"octa/internal/database"
+	"octa/pkg/cache"
+	"octa/pkg/utils"
+)
+
+// SystemDTO is the single-pane view of /console/api/system.
+type SystemDTO struct {
+	Uptime        string           `json:"uptime"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Cache         SystemCacheDTO   `json:"cache"`
+	Database      SystemDBDTO      `json:"database"`
+	Runtime       SystemRuntimeDTO `json:"runtime"`
+	Config        SystemConfigDTO  `json:"config"`
+}
+
+type SystemCacheDTO struct {
+	Enabled   bool    `json:"enabled"`
+	Backend   string  `json:"backend"`
+	Items     int     `json:"items"`
+	UsedBytes int64   `json:"used_bytes"`
+	MaxBytes  int64   `json:"max_bytes"`
+	Hits      uint64  `json:"hits"`
+	Misses    uint64  `json:"misses"`
+	HitRatio  float64 `json:"hit_ratio"` // hits / (hits + misses), 0 before the first lookup
+	Evictions uint64  `json:"evictions"`
+}
+
+type SystemDBDTO struct {
+	FileSize     int64 `json:"file_size"`
+	WALSize      int64 `json:"wal_size"`
+	PhysicalSize int64 `json:"physical_size"` // file + WAL, what database.max_size is compared to
+	LogicalSize  int64 `json:"logical_size"`  // Sum of stored asset sizes
+	MaxSize      int64 `json:"max_size"`
+	AssetCount   int64 `json:"asset_count"`
+}
+
+type SystemRuntimeDTO struct {
+	GoVersion     string  `json:"go_version"`
+	NumCPU        int     `json:"num_cpu"`
+	NumGoroutines int     `json:"num_goroutines"`
+	HeapAlloc     uint64  `json:"heap_alloc"`
+	HeapSys       uint64  `json:"heap_sys"`
+	HeapObjects   uint64  `json:"heap_objects"`
+	NumGC         uint32  `json:"num_gc"`
+	LastGCPauseMs float64 `json:"last_gc_pause_ms"`
+	GCPauseMs     float64 `json:"gc_pause_total_ms"`
+}
+
+// SystemConfigDTO summarizes the running configuration. It reuses the settings export,
+// so secrets (upload secret, console credentials, Redis URL) are never included.
+type SystemConfigDTO struct {
+	Version  string          `json:"version"`
+	Env      string          `json:"env"`
+	Settings config.Settings `json:"settings"`
+}
+
+// GetSystem aggregates cache, database, runtime and configuration details in one response.
+// Nothing is computed here that the stats handler, cache monitor or storage cleaner don't
+// already track; it only puts them side by side.
+// GET /console/api/system
+func GetSystem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	uptime := time.Since(appinfo.StartTime)
+	utils.WriteJSON(w, http.StatusOK, SystemDTO{
+		Uptime:        uptime.String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Cache:         systemCache(),
+		Database:      systemDatabase(),
+		Runtime: SystemRuntimeDTO{
+			GoVersion:     runtime.Version(),
+			NumCPU:        runtime.NumCPU(),
+			NumGoroutines: runtime.NumGoroutine(),
+			HeapAlloc:     m.HeapAlloc,
+			HeapSys:       m.HeapSys,
+			HeapObjects:   m.HeapObjects,
+			NumGC:         m.NumGC,
+			LastGCPauseMs: float64(m.PauseNs[(m.NumGC+255)%256]) / 1e6,
+			GCPauseMs:     float64(m.PauseTotalNs) / 1e6,
+		},
+		Config: SystemConfigDTO{
+			Version:  config.AppConfig.App.Version,
+			Env:      config.AppConfig.Server.Env,
+			Settings: config.AppConfig.ExportSettings(),
+		},
+	})
+}
+
+func systemCache() SystemCacheDTO {
+	conf := config.AppConfig.Cache
+	dto := SystemCacheDTO{Enabled: conf.Enabled, Backend: strings.ToLower(conf.Backend)}
+	if dto.Backend == "" {
+		dto.Backend = cache.DefaultBackend
+	}
+	if globalCache == nil {
+		return dto
+	}
+
+	cs := globalCache.Stats()
+	dto.Items, dto.UsedBytes, dto.MaxBytes = cs.Count, cs.TotalSize, cs.MaxSize
+	dto.Hits, dto.Misses, dto.Evictions = cs.Hits, cs.Misses, cs.Evictions
+	if lookups := cs.Hits + cs.Misses; lookups > 0 {
+		dto.HitRatio = float64(cs.Hits) / float64(lookups)
+	}
+	return dto
+}
+
+func systemDatabase() SystemDBDTO {
+	dto := SystemDBDTO{
+		LogicalSize: appinfo.TotalAssetsSize.Load(),
+		AssetCount:  appinfo.TotalAssetsCount.Load(),
+		MaxSize:     utils.SizeToBytes(config.AppConfig.Database.MaxSize, 2*1024*1024*1024),
+	}
+	// A failed stat leaves the file sizes at 0; the rest of the report is still useful.
+	if fileSize, walSize, err := database.FileSizes(); err == nil {
+		dto.FileSize, dto.WALSize, dto.PhysicalSize = fileSize, walSize, fileSize+walSize
+	}
+	return dto
+}
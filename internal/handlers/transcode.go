@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"net/http"
+	"strings"
+
+	"octa/internal/config"
+	"octa/pkg/logger"
+	"octa/pkg/utils"
+)
+
+// webpVariantSuffix marks the transcoded copy of a stored asset in the cache ("img:<id>:webp").
+const webpVariantSuffix = ":webp"
+
+// acceptsWebP reports whether the client advertised WebP support.
+func acceptsWebP(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "image/webp")
+}
+
+// negotiateWebP returns the WebP variant of a stored JPEG/PNG when image.serve_webp is on
+// and the client accepts it. The variant is encoded once per asset (SingleFlight) and cached.
+// Any failure falls back to the original bytes.
+func negotiateWebP(w http.ResponseWriter, r *http.Request, assetID string, img storedImage) storedImage {
+	if !config.AppConfig.Image.ServeWebP {
+		return img
+	}
+
+	// Same URL, different bytes: shared caches must key on Accept.
+	w.Header().Add("Vary", "Accept")
+
+	if !acceptsWebP(r) || (img.MimeType != "image/jpeg" && img.MimeType != "image/png") {
+		return img
+	}
+
+	variantKey := "img:" + assetID + webpVariantSuffix
+	data, err, _ := requestGroup.Do("transcode:"+assetID, func() (interface{}, error) {
+		if cached, ok := globalCache.Get(variantKey); ok {
+			return cached, nil
+		}
+
+		decoded, _, err := image.Decode(bytes.NewReader(img.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		encoded, err := utils.EncodeWebP(decoded, imageQuality())
+		if err != nil {
+			return nil, err
+		}
+
+		globalCache.Set(variantKey, encoded)
+		return encoded, nil
+	})
+
+	if err != nil {
+		logger.LogWarn("WebP transcode failed for asset %s: %v", assetID, err)
+		return img
+	}
+
+	return storedImage{Data: data.([]byte), MimeType: "image/webp"}
+}
+
+// invalidateImageCache drops a stored asset and its transcoded variants.
+func invalidateImageCache(assetID string) {
+	if globalCache == nil {
+		return
+	}
+	globalCache.Delete("img:" + assetID)
+	globalCache.Delete("img:" + assetID + webpVariantSuffix)
+}
@@ -1,23 +1,33 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/subtle"
 	"errors"
+	"fmt"
 	"image"
+	"image/color"
 	_ "image/gif"  // Support GIF
 	_ "image/jpeg" // Support JPEG
 	_ "image/png"  // Support PNG
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 
 	"octa/internal/appinfo"
 	"octa/internal/config"
 	"octa/internal/database"
+	"octa/internal/moderation"
 
 	"octa/pkg/utils"
 )
@@ -25,16 +35,70 @@ import (
 const (
 	DefaultMaxUploadSize = 5 << 20 // 5 MB
 	DefaultMaxKeyLimit   = 7       // Max slugs per asset
+	DefaultImageQuality  = 80      // JPEG quality when image.quality is unset/invalid
+	KeysPeekWindow       = 8 << 10 // Bytes buffered to find 'keys' before the file part
 
-	// MaxConcurrentDBOps limits the number of active SQLite write transactions.
+	// DefaultMaxConcurrentDBOps limits the number of active SQLite write transactions
+	// when database.max_concurrent_writes is unset/invalid.
 	// Since SQLite allows only one writer at a time (even in WAL mode),
 	// queueing requests in Go memory is more efficient than locking the DB file.
-	MaxConcurrentDBOps = 10
+	DefaultMaxConcurrentDBOps = 10
+
+	DefaultWriteQueueTimeout = 5 * time.Second // Used when database.write_queue_timeout is unset/invalid
 )
 
 // dbGuard acts as a semaphore to limit concurrent database writes.
-// Buffered channel with capacity = MaxConcurrentDBOps.
-var dbGuard = make(chan struct{}, MaxConcurrentDBOps)
+// Buffered channel with capacity = database.max_concurrent_writes, sized on first use.
+var (
+	dbGuard        chan struct{}
+	dbGuardTimeout time.Duration
+	dbGuardOnce    sync.Once
+)
+
+// errWriteBusy is returned when every write slot stays occupied past database.write_queue_timeout.
+var errWriteBusy = errors.New("database write capacity exhausted")
+
+func initDBGuard() {
+	limit := config.AppConfig.Database.MaxConcurrentWrites
+	if limit <= 0 {
+		limit = DefaultMaxConcurrentDBOps
+	}
+	dbGuard = make(chan struct{}, limit)
+
+	timeout, err := time.ParseDuration(config.AppConfig.Database.WriteQueueTimeout)
+	if err != nil || timeout <= 0 {
+		timeout = DefaultWriteQueueTimeout
+	}
+	dbGuardTimeout = timeout
+}
+
+// acquireDBWrite waits for a write slot. Pair a nil result with releaseDBWrite.
+// Returns errWriteBusy after database.write_queue_timeout, or ctx's error if the caller gave up first:
+// under a write flood clients get explicit backpressure instead of queueing without bound.
+func acquireDBWrite(ctx context.Context) error {
+	dbGuardOnce.Do(initDBGuard)
+
+	timer := time.NewTimer(dbGuardTimeout)
+	defer timer.Stop()
+
+	select {
+	case dbGuard <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return errWriteBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func releaseDBWrite() {
+	<-dbGuard
+}
+
+func writeWriteBusy(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	utils.WriteError(w, http.StatusServiceUnavailable, utils.ErrServerBusy, "Too many concurrent writes. Please retry.")
+}
 
 // UploadHandler processes image uploads via multipart/form-data.
 // It includes a concurrency guard to prevent SQLite 'database is locked' errors
@@ -65,6 +129,14 @@ func UploadHandler(w http.ResponseWriter, r *http.Request) {
 	maxUploadSize := utils.SizeToBytes(config.AppConfig.Image.MaxUploadSize, DefaultMaxUploadSize)
 	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 
+	// Early Key Check: if 'keys' precedes the file part, reject bad keys before reading the upload.
+	if keysStr, ok := peekFormField(r, "keys", KeysPeekWindow); ok {
+		if msg := validateKeyCount(parseKeys(keysStr), maxKeyLimit); msg != "" {
+			utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, msg)
+			return
+		}
+	}
+
 	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
 		utils.WriteError(w, http.StatusBadRequest,  utils.ErrRequestBodyTooLarge, "File exceeds size limit.")
 		return
@@ -74,12 +146,8 @@ func UploadHandler(w http.ResponseWriter, r *http.Request) {
 	keysStr := r.FormValue("keys")
 	validKeys := parseKeys(keysStr)
 
-	if len(validKeys) == 0 {
-		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "At least one valid key is required.")
-		return
-	}
-	if len(validKeys) > maxKeyLimit {
-		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, "Too many keys provided.")
+	if msg := validateKeyCount(validKeys, maxKeyLimit); msg != "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, msg)
 		return
 	}
 
@@ -91,74 +159,69 @@ func UploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	if !utils.IsImageFile(header) {
-		utils.WriteError(w, http.StatusUnsupportedMediaType, utils.ErrRequestUnSupportedMedia, "Unsupported file type.")
+	if contentType, ok := utils.DetectImageType(header); !ok {
+		if contentType == "" {
+			contentType = "unknown"
+		}
+		utils.WriteError(w, http.StatusUnsupportedMediaType, utils.ErrRequestUnSupportedMedia,
+			fmt.Sprintf("Unsupported file type '%s'. Allowed types: %s.", contentType, strings.Join(utils.AllowedImageTypes, ", ")))
+		return
+	}
+
+	// Optional Metadata Tags (JSON object, e.g. {"category":"logos"})
+	metadata, hasMetadata, err := parseMetadata(r.FormValue("metadata"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, err.Error())
 		return
 	}
 
 	//  Image Processing (CPU Intensive - Parallelized)
 	// We do this BEFORE acquiring the DB lock to maximize throughput.
+	// Icon set: one upload, several size-suffixed assets (POST /upload?generate=iconset)
+	if generate := r.URL.Query().Get("generate"); generate != "" {
+		if generate != "iconset" {
+			utils.WriteError(w, http.StatusBadRequest, utils.ErrRequestInvalid, fmt.Sprintf("Unsupported generate value '%s'. Supported: iconset.", generate))
+			return
+		}
+		uploadIconSet(w, r, file, validKeys, metadata, hasMetadata)
+		return
+	}
+
 	finalData, meta, err := processUploadImage(file, r)
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, utils.ErrImageProcessingFailed, err.Error())
+		writeUploadError(w, err)
 		return
 	}
 
 	// This block prevents "database is locked" errors by queueing requests here.
-	dbGuard <- struct{}{}
-	defer func() { <-dbGuard }() // Release token when function exits
+	if err := acquireDBWrite(r.Context()); err != nil {
+		writeWriteBusy(w)
+		return
+	}
+	defer releaseDBWrite() // Release token when function exits
 
 	// Database Transaction (Serialized by Semaphore)
 	tx := database.DB.Begin()
+	if tx.Error != nil {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to start transaction.")
+		return
+	}
+
+	// Rollback guard: a leaked tx would hold SQLite's single writer and stall every upload.
+	committed := false
 	defer func() {
-		if r := recover(); r != nil {
+		if !committed {
 			tx.Rollback()
 		}
 	}()
 
 	primaryKey := validKeys[0] // Authority Key
-	var targetAssetID string
-	var actionType string
-	var oldSize int64 = 0
-
-	var existingMapping database.KeyMapping
-
-	// UPSERT LOGIC
-	if err := tx.Where("key = ?", primaryKey).First(&existingMapping).Error; err == nil {
-		// UPDATE
-		targetAssetID = existingMapping.ImageID
-		actionType = "updated"
-
-		tx.Model(&database.Image{}).Where("id = ?", targetAssetID).Select("size").Scan(&oldSize)
-
-		updateData := database.Image{
-			Data: finalData, Width: meta.Width, Height: meta.Height, Format: meta.Format, Size: meta.Size,
-			UpdatedAt: time.Now(),
-		}
-		if err := tx.Model(&database.Image{}).Where("id = ?", targetAssetID).Updates(updateData).Error; err != nil {
-			tx.Rollback()
-			utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to update image.")
-			return
-		}
-	} else {
-		// CREATE
-		targetAssetID = uuid.New().String()
-		actionType = "created"
-
-		newImage := database.Image{
-			ID: targetAssetID, Data: finalData, Width: meta.Width, Height: meta.Height, Format: meta.Format, Size: meta.Size,
-		}
-		if err := tx.Create(&newImage).Error; err != nil {
-			tx.Rollback()
-			utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to save image.")
-			return
-		}
-		if err := tx.Create(&database.KeyMapping{Key: primaryKey, ImageID: targetAssetID}).Error; err != nil {
-			tx.Rollback()
-			utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Failed to map primary key.")
-			return
-		}
+	saved, errMsg := upsertKeyImage(tx, primaryKey, finalData, meta, metadata, hasMetadata)
+	if errMsg != "" {
+		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, errMsg)
+		return
 	}
+	targetAssetID, actionType, oldSize := saved.AssetID, saved.Action, saved.OldSize
 
 	// Secondary Keys Logic (Ignore if taken)
 	assignedKeys := []string{primaryKey}
@@ -179,6 +242,7 @@ func UploadHandler(w http.ResponseWriter, r *http.Request) {
 		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Transaction commit failed.")
 		return
 	}
+	committed = true
 
 	// Post-Transaction (Stats & Cache)
 	updateStatsAndCache(actionType, targetAssetID, assignedKeys, meta.Size, oldSize)
@@ -226,21 +290,16 @@ func DeleteAPIHandler(w http.ResponseWriter, r *http.Request) {
 		assetID = mapping.ImageID
 	}
 
-	// CoreDeleteAsset logic (assumed to be available or imported)
-	// For this snippet, we assume it's a wrapper around DB delete + Cache clear
-	if err := database.DB.Where("id = ?", assetID).Delete(&database.Image{}).Error; err != nil {
+	// Transactional delete: mappings, blob, stats and cache in one place
+	if err := CoreDeleteAsset(r.Context(), assetID); err != nil {
+		if errors.Is(err, utils.ErrAssetNotFound) {
+			utils.WriteError(w, http.StatusNotFound, utils.ErrResourceNotFound, "Asset not found.")
+			return
+		}
 		utils.WriteError(w, http.StatusInternalServerError, utils.ErrServerInternal, "Deletion failed.")
 		return
 	}
 
-	// Clean up mappings
-	database.DB.Where("image_id = ?", assetID).Delete(&database.KeyMapping{})
-
-	// Clear Cache
-	if globalCache != nil {
-		globalCache.Delete("img:" + assetID)
-	}
-
 	utils.WriteJSON(w, http.StatusOK, map[string]string{
 		"status": "success",
 		"action": "deleted",
@@ -274,6 +333,119 @@ func parseKeys(keysStr string) []string {
 	return validKeys
 }
 
+// validateKeyCount returns a client-facing error message, or "" if the key list is acceptable.
+func validateKeyCount(keys []string, maxKeyLimit int) string {
+	if len(keys) == 0 {
+		return "At least one valid key is required."
+	}
+	if len(keys) > maxKeyLimit {
+		return "Too many keys provided."
+	}
+	return ""
+}
+
+// peekFormField looks for a small multipart field within the first window bytes of the body
+// without consuming it. ok=false means the field was not found in time (e.g. it comes after
+// the file part, or its value is cut off) and the caller must fall back to the full parse.
+func peekFormField(r *http.Request, field string, window int) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", false
+	}
+
+	// Re-attach the buffered reader so ParseMultipartForm still sees the whole body.
+	br := bufio.NewReaderSize(r.Body, window)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{br, r.Body}
+
+	head, _ := br.Peek(window)
+	mr := multipart.NewReader(bytes.NewReader(head), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return "", false
+		}
+		if part.FileName() != "" {
+			return "", false // Reached the upload itself; keys (if any) come later
+		}
+		if part.FormName() != field {
+			continue
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return "", false // Value truncated at the window edge
+		}
+		return string(value), true
+	}
+}
+
+// upsertResult describes the asset written by upsertKeyImage.
+type upsertResult struct {
+	AssetID string
+	Action  string // "created" or "updated"
+	OldSize int64  // Previous blob size for "updated" (stats)
+}
+
+// upsertKeyImage replaces the image behind key, or creates a new asset mapped to it.
+// It returns a client-facing error message, or "" on success; the caller owns tx.
+func upsertKeyImage(tx *gorm.DB, key string, data []byte, meta ImageMeta, metadata string, hasMetadata bool) (upsertResult, string) {
+	var res upsertResult
+	var existingMapping database.KeyMapping
+
+	if err := tx.Where("key = ?", key).First(&existingMapping).Error; err == nil {
+		// UPDATE
+		res.AssetID = existingMapping.ImageID
+		res.Action = "updated"
+
+		tx.Model(&database.Image{}).Where("id = ?", res.AssetID).Select("size").Scan(&res.OldSize)
+
+		updateData := database.Image{
+			Data: data, Width: meta.Width, Height: meta.Height, Format: meta.Format, Size: meta.Size,
+			UpdatedAt: time.Now(),
+		}
+		if err := tx.Model(&database.Image{}).Where("id = ?", res.AssetID).Updates(updateData).Error; err != nil {
+			return res, "Failed to update image."
+		}
+		// Separate update so "{}" can clear tags (Updates skips zero values); absent field keeps them.
+		if hasMetadata {
+			if err := tx.Model(&database.Image{}).Where("id = ?", res.AssetID).Update("metadata", metadata).Error; err != nil {
+				return res, "Failed to update metadata."
+			}
+		}
+		return res, ""
+	}
+
+	// CREATE
+	res.AssetID = uuid.New().String()
+	res.Action = "created"
+
+	newImage := database.Image{
+		ID: res.AssetID, Data: data, Width: meta.Width, Height: meta.Height, Format: meta.Format, Size: meta.Size,
+		Metadata: metadata,
+	}
+	if err := tx.Create(&newImage).Error; err != nil {
+		return res, "Failed to save image."
+	}
+	if err := tx.Create(&database.KeyMapping{Key: key, ImageID: res.AssetID}).Error; err != nil {
+		return res, "Failed to map primary key."
+	}
+	return res, ""
+}
+
+// writeUploadError maps processing failures (moderation included) to responses.
+func writeUploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, moderation.ErrRejected):
+		utils.WriteError(w, http.StatusUnprocessableEntity, utils.ErrContentRejected, err.Error())
+	case errors.Is(err, moderation.ErrUnavailable):
+		utils.WriteError(w, http.StatusServiceUnavailable, utils.ErrUpstreamFailed, "Moderation service unavailable, try again later.")
+	default:
+		utils.WriteError(w, http.StatusBadRequest, utils.ErrImageProcessingFailed, err.Error())
+	}
+}
+
 func processUploadImage(file io.Reader, r *http.Request) ([]byte, ImageMeta, error) {
 	var finalData []byte
 	var meta ImageMeta
@@ -287,12 +459,34 @@ func processUploadImage(file io.Reader, r *http.Request) ([]byte, ImageMeta, err
 		if err != nil {
 			return nil, meta, errors.New("file is not a valid image")
 		}
+		if moderation.Enabled() {
+			img, _, err := image.Decode(bytes.NewReader(fileBytes))
+			if err != nil {
+				return nil, meta, errors.New("corrupt image data")
+			}
+			if err := moderation.Check(r.Context(), img); err != nil {
+				return nil, meta, err
+			}
+		}
 		finalData = fileBytes
 		meta = ImageMeta{Width: dcfg.Width, Height: dcfg.Height, Format: formatName, Size: int64(len(fileBytes))}
 	} else {
-		img, _, err := image.Decode(file)
+		format := r.FormValue("format")
+		if format == "" {
+			format = config.AppConfig.Image.UploadFormat
+		}
+		if !utils.ProcessFormats[format] {
+			return nil, meta, errors.New("format must be jpeg, png or webp")
+		}
+
+		background, err := uploadBackground(r)
 		if err != nil {
-			return nil, meta, errors.New("corrupt image data")
+			return nil, meta, err
+		}
+
+		img, err := decodeUploadImage(file, r)
+		if err != nil {
+			return nil, meta, err
 		}
 		targetSize := utils.ParseInt(r.FormValue("size"), 256, 16, 2048)
 		targetScale := utils.ParseInt(r.FormValue("scale"), 75, 1, 100)
@@ -301,32 +495,74 @@ func processUploadImage(file io.Reader, r *http.Request) ([]byte, ImageMeta, err
 			mode = "square"
 		}
 
-		buf, w, h, err := utils.ProcessImage(img, utils.ProcessOptions{
-			Mode: mode, Size: targetSize, Scale: targetScale, Quality: 85,
+		padding := utils.ParseInt(r.FormValue("padding"), 0, 0, utils.MaxPaddingPercent)
+
+		out, err := utils.ProcessImage(img, utils.ProcessOptions{
+			Mode: mode, Size: targetSize, Scale: targetScale, Quality: imageQuality(),
+			Format: format, Background: background, Padding: padding, Watermark: watermarkRequested(r),
 		})
 		if err != nil {
 			return nil, meta, err
 		}
-		finalData = buf.Bytes()
-		meta = ImageMeta{Width: w, Height: h, Format: "jpeg", Size: int64(buf.Len())}
+		finalData = out.Data
+		meta = ImageMeta{Width: out.Width, Height: out.Height, Format: out.Format, Size: int64(len(out.Data))}
 	}
 	return finalData, meta, nil
 }
 
+// decodeUploadImage decodes the uploaded file and runs it through moderation.
+func decodeUploadImage(file io.Reader, r *http.Request) (image.Image, error) {
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, errors.New("corrupt image data")
+	}
+	if err := moderation.Check(r.Context(), img); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// uploadBackground parses the optional 'background' form field. nil means the format's default.
+func uploadBackground(r *http.Request) (color.Color, error) {
+	raw := r.FormValue("background")
+	if raw == "" {
+		return nil, nil
+	}
+	c, err := utils.ParseColor(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid background color '%s'", raw)
+	}
+	return c, nil
+}
+
+// watermarkRequested resolves the 'watermark' form field against image.watermark.enabled.
+func watermarkRequested(r *http.Request) bool {
+	if !utils.WatermarkLoaded() {
+		return false
+	}
+	if v, err := strconv.ParseBool(r.FormValue("watermark")); err == nil {
+		return v
+	}
+	return config.AppConfig.Image.Watermark.Enabled
+}
+
+// imageQuality returns the configured lossy encoding quality (1-100).
+func imageQuality() int {
+	q := config.AppConfig.Image.Quality
+	if q < 1 || q > 100 {
+		return DefaultImageQuality
+	}
+	return q
+}
+
 func updateStatsAndCache(actionType, assetID string, keys []string, newSize, oldSize int64) {
 	if actionType == "updated" {
 		appinfo.RemoveAsset(oldSize)
 		appinfo.AddAsset(newSize)
-		if globalCache != nil {
-			globalCache.Delete("img:" + assetID)
-		}
+		invalidateImageCache(assetID)
 	} else {
 		appinfo.AddAsset(newSize)
 	}
 
-	if globalCache != nil {
-		for _, k := range keys {
-			globalCache.Delete("map:" + k)
-		}
-	}
+	invalidateKeyCache(keys)
 }
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"octa/internal/database"
+	"octa/pkg/generator/styles"
+)
+
+// uploadTestAvatar posts a PNG for keys through UploadHandler and removes it after the test.
+func uploadTestAvatar(t *testing.T, keys string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("keys", keys)
+	part, err := mw.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(encodeTestImage(t, "png"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("X-Secret-Key", "test-secret")
+	rec := httptest.NewRecorder()
+	UploadHandler(rec, req)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("upload: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	t.Cleanup(func() {
+		var m database.KeyMapping
+		if database.DB.First(&m, "key = ?", keys).Error == nil {
+			database.DB.Where("image_id = ?", m.ImageID).Delete(&database.KeyMapping{})
+			database.DB.Delete(&database.Image{}, "id = ?", m.ImageID)
+		}
+	})
+}
+
+func TestUploadInvalidatesGeneratedFallback(t *testing.T) {
+	const key = "stale-fallback"
+	query := url.Values{"size": {"64"}}
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		ServeUserAvatar(rec, httptest.NewRequest(http.MethodGet, "/u/"+key+"?"+query.Encode(), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /u/%s: status = %d", key, rec.Code)
+		}
+		return rec
+	}
+	genKey, _ := buildCacheKey("gen", key, styles.ResolveOptions(key, query), query)
+
+	// No asset yet: the generated fallback is served and cached under gen:<key>?...
+	fallback := get().Body.Bytes()
+	if _, ok := globalCache.Get(genKey); !ok {
+		t.Fatalf("generated fallback was not cached under %q", genKey)
+	}
+
+	uploadTestAvatar(t, key)
+
+	if _, ok := globalCache.Get(genKey); ok {
+		t.Errorf("fallback %q is still cached after the upload", genKey)
+	}
+	if got := get().Body.Bytes(); bytes.Equal(got, fallback) {
+		t.Error("the stale generated fallback is still served after the upload")
+	}
+}
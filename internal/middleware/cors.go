@@ -2,13 +2,30 @@ package middleware
 
 import (
 	"net/http"
+	"octa/internal/config"
 	"octa/pkg/utils"
+	"strings"
 )
 
 // CorsMiddleware handles Cross-Origin Resource Sharing with Wildcard Subdomain support.
 func CorsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
+		// Public images: readable from any origin, never with credentials.
+		if isPublicImageRequest(r) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, If-None-Match")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Octa-Degraded")
+
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		requestOrigin := r.Header.Get("Origin")
 		referer := r.Header.Get("Referer")
 origin := requestOrigin
@@ -37,3 +54,16 @@ if origin == ""{
 	})
 }
 
+// isPublicImageRequest reports whether r reads a public image route (/avatar/..., /u/...)
+// and security.public_image_cors is on. Writes and the API keep the origin whitelist.
+func isPublicImageRequest(r *http.Request) bool {
+	if !config.AppConfig.Security.PublicImageCors {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, "/avatar/") || strings.HasPrefix(r.URL.Path, "/u/")
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"octa/internal/config"
+)
+
+// ResponseHeadersMiddleware adds the headers from server.response_headers (HSTS, CSP, ...) to every response.
+// They are set before the handler runs, so per-response headers written later always win.
+func ResponseHeadersMiddleware(next http.Handler) http.Handler {
+	headers := make(http.Header)
+	for name, value := range config.AppConfig.Server.ResponseHeaders {
+		// Viper lowercases map keys: restore the canonical form.
+		name = strings.TrimSpace(name)
+		if name == "" || config.IsReservedResponseHeader(name) {
+			continue
+		}
+		headers.Set(name, value)
+	}
+
+	if len(headers) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		for name := range headers {
+			h.Set(name, headers.Get(name))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/fatih/color"
+
+	"octa/internal/appinfo"
 )
 
 // ResponseWriter wrapper to capture status code and size
@@ -53,6 +55,9 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 
+		// Live traffic stats for the console (atomic, never blocks)
+		appinfo.RecordRequest(ww.statusCode, duration)
+
 		
 		var statusStr string
 		code := ww.statusCode
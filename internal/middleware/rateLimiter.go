@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"container/list"
 	"net/http"
 	"sync"
 	"time"
@@ -21,16 +22,24 @@ const (
 	// Garbage Collection
 	VisitorTTL      = 5 * time.Minute // Time before an inactive IP is removed from memory
 	CleanupInterval = 3 * time.Minute // Frequency of the cleanup routine
+
+	// DefaultMaxVisitors caps tracked IPs when security.rate_limit.max_visitors is 0 or unset.
+	DefaultMaxVisitors = 100000
 )
 
 type visitor struct {
+	ip       string
 	limiter  *rate.Limiter
 	lastSeen time.Time
 }
 
+// visitors indexes the entries of visitorOrder, which is kept most recently seen first.
+// The cleanup routine only bounds memory between ticks; the list lets getVisitor drop the
+// least recently seen IP in O(1) when a flood of new IPs hits the cap.
 var (
-	visitors = make(map[string]*visitor)
-	mu       sync.Mutex
+	visitors     = make(map[string]*list.Element)
+	visitorOrder = list.New()
+	mu           sync.Mutex
 )
 
 func init() {
@@ -43,10 +52,12 @@ func startCleanupRoutine() {
 	ticker := time.NewTicker(CleanupInterval)
 	for range ticker.C {
 		mu.Lock()
-		for ip, v := range visitors {
-			if time.Since(v.lastSeen) > VisitorTTL {
-				delete(visitors, ip)
+		// Oldest entries sit at the back, so stop at the first one still active
+		for e := visitorOrder.Back(); e != nil; e = visitorOrder.Back() {
+			if time.Since(e.Value.(*visitor).lastSeen) <= VisitorTTL {
+				break
 			}
+			removeVisitor(e)
 		}
 		mu.Unlock()
 	}
@@ -56,7 +67,7 @@ func getVisitor(ip string) *rate.Limiter {
 	mu.Lock()
 	defer mu.Unlock()
 
-	v, exists := visitors[ip]
+	e, exists := visitors[ip]
 	if !exists {
 		conf := config.AppConfig.Security.RateLimit
 
@@ -80,14 +91,30 @@ func getVisitor(ip string) *rate.Limiter {
 
 		limiter := rate.NewLimiter(rate.Limit(rps), burst)
 
-		visitors[ip] = &visitor{limiter, time.Now()}
+		maxVisitors := conf.MaxVisitors
+		if maxVisitors <= 0 {
+			maxVisitors = DefaultMaxVisitors
+		}
+		for len(visitors) >= maxVisitors {
+			removeVisitor(visitorOrder.Back())
+		}
+
+		visitors[ip] = visitorOrder.PushFront(&visitor{ip, limiter, time.Now()})
 		return limiter
 	}
 
+	v := e.Value.(*visitor)
 	v.lastSeen = time.Now()
+	visitorOrder.MoveToFront(e)
 	return v.limiter
 }
 
+// removeVisitor drops an entry from both the list and the index. The caller must hold mu.
+func removeVisitor(e *list.Element) {
+	visitorOrder.Remove(e)
+	delete(visitors, e.Value.(*visitor).ip)
+}
+
 // RateLimitMiddleware enforces request quotas per IP address.
 // Blocks excessive requests with a 429 JSON response.
 func RateLimitMiddleware(next http.Handler) http.Handler {
@@ -0,0 +1,148 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"time"
+
+	"octa/internal/config"
+	"octa/pkg/logger"
+)
+
+const (
+	// DefaultTimeout bounds a single moderation round-trip.
+	DefaultTimeout = 5 * time.Second
+
+	// sampleQuality is the JPEG quality of the copy sent to the moderation endpoint.
+	sampleQuality = 85
+)
+
+var (
+	// ErrRejected means the moderator inspected the image and refused it.
+	ErrRejected = errors.New("content rejected by moderation")
+
+	// ErrUnavailable means the moderator could not be reached (fail-closed mode only).
+	ErrUnavailable = errors.New("moderation service unavailable")
+)
+
+// Verdict is the moderator's decision for one image.
+type Verdict struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Moderator inspects a decoded upload before it is stored.
+// Implementations may call an external API or run a local classifier.
+type Moderator interface {
+	Check(ctx context.Context, img image.Image) (Verdict, error)
+}
+
+var (
+	active   Moderator
+	failOpen bool
+)
+
+// Init wires the moderator described by image.moderation. Does nothing when disabled.
+func Init() {
+	cfg := config.AppConfig.Image.Moderation
+	if !cfg.Enabled {
+		return
+	}
+
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	failOpen = cfg.FailOpen
+	active = &HTTPModerator{
+		Endpoint: cfg.Endpoint,
+		APIKey:   cfg.APIKey,
+		Client:   &http.Client{Timeout: timeout},
+	}
+
+	logger.LogInfo("🛡️  Upload moderation enabled | Endpoint: %s | Fail-open: %v", cfg.Endpoint, failOpen)
+}
+
+// SetModerator installs a custom moderator (e.g. a local NSFW classifier). nil disables moderation.
+func SetModerator(m Moderator, allowOnError bool) {
+	active = m
+	failOpen = allowOnError
+}
+
+// Enabled reports whether uploads are currently moderated.
+func Enabled() bool {
+	return active != nil
+}
+
+// Check runs the active moderator. It returns nil when moderation is disabled,
+// an ErrRejected-wrapped error for refused content and, unless fail-open is set,
+// an ErrUnavailable-wrapped error when the moderator itself fails.
+func Check(ctx context.Context, img image.Image) error {
+	if active == nil {
+		return nil
+	}
+
+	verdict, err := active.Check(ctx, img)
+	if err != nil {
+		if failOpen {
+			logger.LogWarn("Moderation check failed, allowing upload (fail-open): %v", err)
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	if !verdict.Allowed {
+		if verdict.Reason != "" {
+			return fmt.Errorf("%w: %s", ErrRejected, verdict.Reason)
+		}
+		return ErrRejected
+	}
+	return nil
+}
+
+// HTTPModerator posts a JPEG copy of the image to an external endpoint.
+// The endpoint must answer 2xx with a JSON body: {"allowed": bool, "reason": "..."}.
+type HTTPModerator struct {
+	Endpoint string
+	APIKey   string
+	Client   *http.Client
+}
+
+func (m *HTTPModerator) Check(ctx context.Context, img image.Image) (Verdict, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: sampleQuality}); err != nil {
+		return Verdict{}, fmt.Errorf("encode sample: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.Endpoint, &buf)
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "image/jpeg")
+	if m.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.APIKey)
+	}
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Verdict{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var verdict Verdict
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		return Verdict{}, fmt.Errorf("decode verdict: %w", err)
+	}
+	return verdict, nil
+}
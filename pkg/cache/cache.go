@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"strings"
+	"time"
+
+	"octa/internal/config"
+	"octa/pkg/logger"
+)
+
+// Cache backends (cache.backend).
+const (
+	BackendMemory = "memory" // In-process, per instance (default)
+	BackendRedis  = "redis"  // Shared by every instance; invalidations apply everywhere
+
+	DefaultBackend = BackendMemory
+)
+
+// Cache is what the handlers need from a cache backend. Implementations must be safe for
+// concurrent use and treat a disabled or unreachable backend as a miss, never as an error:
+// the database is always the source of truth.
+type Cache interface {
+	// Get returns the cached bytes and true on a hit.
+	Get(key string) ([]byte, bool)
+
+	// Set stores data with the configured TTL. Backends may skip values they consider too large.
+	Set(key string, data []byte)
+
+	// SetWithTTL is Set with a per-item TTL; ttl <= 0 uses the configured one.
+	SetWithTTL(key string, data []byte, ttl time.Duration)
+
+	// Delete removes a single key.
+	Delete(key string)
+
+	// DeletePrefix removes every key starting with one of the prefixes and returns how many were dropped.
+	DeletePrefix(prefixes ...string) int
+
+	// Stats reports usage and hit/miss counters for the admin stats.
+	Stats() Stats
+}
+
+// New builds the backend selected by cache.backend. A disabled cache is always the
+// in-memory pass-through, whatever the backend.
+func New() Cache {
+	conf := config.AppConfig.Cache
+	if conf.Enabled && strings.ToLower(conf.Backend) == BackendRedis {
+		return NewRedis(conf.RedisURL, configuredTTL())
+	}
+	return NewMemory()
+}
+
+// configuredTTL parses cache.ttl, falling back to DefaultTTL.
+func configuredTTL() time.Duration {
+	ttlStr := config.AppConfig.Cache.TTL
+	ttl, err := time.ParseDuration(ttlStr)
+	if err != nil {
+		ttl = DefaultTTL
+
+		logger.LogWarn("Invalid cache TTL '%s', using default 30m", ttlStr)
+	}
+	return ttl
+}
@@ -4,8 +4,9 @@ package cache
 
 import (
 	"log"
-	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"octa/internal/config"
@@ -18,6 +19,9 @@ const (
 	DefaultMaxSize = 100 // 100 MB Limit
 	DefaultTTL     = 30 * time.Minute
 
+	// MaxItemSize: Larger values are never cached (by any backend).
+	MaxItemSize = 512 * 1024
+
 	// GCInterval: Expired items cleanup frequency.
 	// 10 minutes is a good balance to avoid frequent locking overhead.
 	GCInterval = 5 * time.Minute
@@ -28,24 +32,74 @@ const (
 	MonitorInterval = 30 * time.Minute
 )
 
+// Eviction policies (cache.eviction_policy). They decide which items prune drops first.
+const (
+	PolicyTTL  = "ttl"  // Soonest to expire first
+	PolicyLRU  = "lru"  // Least recently read first (default)
+	PolicyLFU  = "lfu"  // Least frequently read first; suits a stable hot-set
+	PolicyFIFO = "fifo" // Oldest insert first; cheapest bookkeeping
+
+	DefaultEvictionPolicy = PolicyLRU
+
+	// EvictionSampleSize: Keys inspected per eviction round. Higher is closer to the
+	// exact policy order, lower is cheaper (Redis uses 5 by default).
+	EvictionSampleSize = 8
+)
+
+// EvictionPolicies lists the accepted cache.eviction_policy values.
+var EvictionPolicies = map[string]bool{
+	PolicyTTL: true, PolicyLRU: true, PolicyLFU: true, PolicyFIFO: true,
+}
+
 type Item struct {
 	Data      []byte
 	ExpiresAt time.Time
+	CreatedAt time.Time
 	Size      int64
+
+	// Access tracking, updated atomically under the read lock.
+	lastAccess atomic.Int64 // UnixNano
+	hits       atomic.Uint64
 }
 
-type MemoryCache struct {
+// ShardCount is the number of independently locked partitions of the cache.
+// Keys are spread by FNV-1a hash so hot reads on different keys don't contend on one lock.
+const ShardCount = 32
+
+// shard is a self-contained slice of the cache with its own lock and byte budget.
+type shard struct {
 	sync.RWMutex
-	items     map[string]Item
+	items     map[string]*Item
 	totalSize int64
 	maxSize   int64
-	ttl       time.Duration
-	enabled   bool
 }
 
-// New initializes the in-memory cache system.
+type MemoryCache struct {
+	shards  []*shard
+	maxSize int64
+	ttl     time.Duration
+	enabled bool
+	policy  string
+
+	// Lifetime counters for Stats(), updated atomically.
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// Stats is a point-in-time snapshot of cache usage and effectiveness.
+type Stats struct {
+	Count     int    `json:"count"`
+	TotalSize int64  `json:"total_size"`
+	MaxSize   int64  `json:"max_size"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"` // Items dropped by prune to stay under the budget
+}
+
+// NewMemory initializes the in-memory cache system.
 // It configures size limits and starts background maintenance routines (GC & Monitor).
-func New() *MemoryCache {
+func NewMemory() *MemoryCache {
 
 	limitMB := int64(config.AppConfig.Cache.MaxCapacity)
 	if limitMB <= 0 {
@@ -53,31 +107,40 @@ func New() *MemoryCache {
 	}
 	maxSize := limitMB * 1024 * 1024
 
-	ttlStr := config.AppConfig.Cache.TTL
-	ttl, err := time.ParseDuration(ttlStr)
-	if err != nil {
-		ttl = DefaultTTL
+	ttl := configuredTTL()
 
-		logger.LogWarn("Invalid cache TTL '%s', using default 30m", ttlStr)
+	policy := strings.ToLower(config.AppConfig.Cache.EvictionPolicy)
+	if !EvictionPolicies[policy] {
+		if policy != "" {
+			logger.LogWarn("Unknown cache eviction policy '%s', using '%s'", policy, DefaultEvictionPolicy)
+		}
+		policy = DefaultEvictionPolicy
 	}
 
 	isEnabled := config.AppConfig.Cache.Enabled
 	c := &MemoryCache{
-		// items:   make(map[string]Item),
 		maxSize: maxSize,
 		ttl:     ttl,
 		enabled: isEnabled,
+		policy:  policy,
 	}
 
 	if c.enabled {
-		c.items = make(map[string]Item)
+		// Each shard gets an equal share of the byte budget.
+		c.shards = make([]*shard, ShardCount)
+		for i := range c.shards {
+			c.shards[i] = &shard{
+				items:   make(map[string]*Item),
+				maxSize: maxSize / ShardCount,
+			}
+		}
 
 		// Go Workers
 		go c.startGC()      // Garbage Worker
 		go c.startMonitor() // Statistics Worker
 
 		
-		logger.LogInfo("Memory Cache Initialized: %d MB Limit, TTL: %s", limitMB, ttl)
+		logger.LogInfo("Memory Cache Initialized: %d MB Limit (%d shards), TTL: %s, Eviction: %s", limitMB, ShardCount, ttl, policy)
 	} else {
 		
 		logger.LogWarn("Memory Cache is DISABLED via config (Running in pass-through mode).")
@@ -85,19 +148,36 @@ func New() *MemoryCache {
 	return c
 }
 
+// shardFor picks the shard owning key (inline FNV-1a, no allocation).
+func (c *MemoryCache) shardFor(key string) *shard {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return c.shards[h%uint32(len(c.shards))]
+}
+
 // Set stores a value in the cache with the configured TTL.
-// Large items (>512KB) are skipped to preserve RAM for high-frequency small assets.
 func (c *MemoryCache) Set(key string, data []byte) {
+	c.SetWithTTL(key, data, c.ttl)
+}
+
+// SetWithTTL stores a value that expires after ttl (the configured TTL when ttl <= 0).
+// Large items (>512KB) are skipped to preserve RAM for high-frequency small assets.
+func (c *MemoryCache) SetWithTTL(key string, data []byte, ttl time.Duration) {
 	if !c.enabled {
 		return
 	}
 
-	c.Lock()
-	defer c.Unlock()
+	s := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
 
 	size := int64(len(data))
 
 	// Safety Check: Single item shouldn't take more than 50% of the cache.
+	// The limit is global, not per shard, so small caches keep the same MaxItemSize cutoff that preload uses.
 	if size > c.maxSize/2 {
 		return
 	}
@@ -105,26 +185,35 @@ func (c *MemoryCache) Set(key string, data []byte) {
 	// Optimization Strategy:
 	// Files larger than 512KB are better handled by the OS Page Cache (SQLite).
 	// Storing them in Go Heap creates GC pressure. We strictly cache small avatars/thumbnails.
-	if size > 512*1024 {
+	if size > MaxItemSize {
 		return
 	}
 
 	// Eviction Strategy: If full, make room.
-	if c.totalSize+size > c.maxSize {
-		c.prune(size)
+	if s.totalSize+size > s.maxSize {
+		c.prune(s)
 	}
 
 	// Overwrite logic: Remove old size before adding new
-	if oldItem, exists := c.items[key]; exists {
-		c.totalSize -= oldItem.Size
+	if oldItem, exists := s.items[key]; exists {
+		s.totalSize -= oldItem.Size
+	}
+
+	if ttl <= 0 {
+		ttl = c.ttl
 	}
 
-	c.items[key] = Item{
+	now := time.Now()
+	item := &Item{
 		Data:      data,
-		ExpiresAt: time.Now().Add(c.ttl),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
 		Size:      size,
 	}
-	c.totalSize += size
+	item.lastAccess.Store(now.UnixNano())
+
+	s.items[key] = item
+	s.totalSize += size
 }
 
 // Get retrieves an item if it exists and hasn't expired.
@@ -133,16 +222,24 @@ func (c *MemoryCache) Get(key string) ([]byte, bool) {
 		return nil, false
 	}
 
-	c.RLock()
-	defer c.RUnlock()
+	s := c.shardFor(key)
+	s.RLock()
+	defer s.RUnlock()
 
-	item, found := c.items[key]
+	item, found := s.items[key]
 	if !found {
+		c.misses.Add(1)
 		return nil, false
 	}
-	if time.Now().After(item.ExpiresAt) {
+	now := time.Now()
+	if now.After(item.ExpiresAt) {
+		c.misses.Add(1)
 		return nil, false
 	}
+
+	item.lastAccess.Store(now.UnixNano())
+	item.hits.Add(1)
+	c.hits.Add(1)
 	return item.Data, true
 }
 
@@ -152,76 +249,115 @@ func (c *MemoryCache) Delete(key string) {
 		return
 	}
 
-	c.Lock()
-	defer c.Unlock()
+	s := c.shardFor(key)
+	s.Lock()
+	defer s.Unlock()
 
-	if item, found := c.items[key]; found {
-		delete(c.items, key)
-		c.totalSize -= item.Size
+	if item, found := s.items[key]; found {
+		delete(s.items, key)
+		s.totalSize -= item.Size
 		// log.Printf("🧹 Cache Invalidated: %s", key)
 	}
 }
 
-// prune evicts items sorted by expiration time until memory usage drops below 80%.
-// Note: This operation holds the Write Lock.
-func (c *MemoryCache) prune(needed int64) {
-	// Theoretically, it won't come here, but I wanted to use it anyway.
-	if c.items == nil || len(c.items) == 0 {
-		return
+// DeletePrefix removes every item whose key starts with one of the prefixes and returns how many were dropped.
+// Keys are hashed across shards, so this scans the whole cache once (one shard lock at a time).
+// Meant for rare invalidations such as uploads, not for hot paths.
+func (c *MemoryCache) DeletePrefix(prefixes ...string) int {
+	if !c.enabled || len(prefixes) == 0 {
+		return 0
 	}
 
-	// Target: Free up to 20% of capacity to avoid frequent pruning
-	targetSize := int64(float64(c.maxSize) * 0.80)
-
-	type candidate struct {
-		Key       string
-		ExpiresAt time.Time
-		Size      int64
+	removed := 0
+	for _, s := range c.shards {
+		s.Lock()
+		for k, item := range s.items {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(k, prefix) {
+					delete(s.items, k)
+					s.totalSize -= item.Size
+					removed++
+					break
+				}
+			}
+		}
+		s.Unlock()
 	}
+	return removed
+}
 
-	// Collect candidates (O(N) allocation)
-	candidates := make([]candidate, 0, len(c.items))
-	for k, v := range c.items {
-		candidates = append(candidates, candidate{k, v.ExpiresAt, v.Size})
-	}
+// prune evicts items of one shard until it drops below 80% of its budget.
+// Like Redis, it approximates the eviction policy by sampling: each round looks at
+// EvictionSampleSize random keys and drops the one with the lowest score.
+// No full sort or candidate slice is built, so the shard's Write Lock is held only briefly.
+// Note: The caller must hold the shard's Write Lock.
+func (c *MemoryCache) prune(s *shard) {
+	// Target: Free up to 20% of capacity to avoid frequent pruning
+	targetSize := int64(float64(s.maxSize) * 0.80)
+	now := time.Now()
 
-	// Sort by Expiration: Delete items that will expire soonest first.
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].ExpiresAt.Before(candidates[j].ExpiresAt)
-	})
+	for s.totalSize > targetSize && len(s.items) > 0 {
+		var victimKey string
+		var victim *Item
+		var victimScore int64
 
-	for _, cand := range candidates {
-		if c.totalSize <= targetSize {
-			break
+		// Go map iteration starts at a random position, which gives us the sample.
+		sampled := 0
+		for k, v := range s.items {
+			if now.After(v.ExpiresAt) {
+				victimKey, victim = k, v // Already dead, no need to look further
+				break
+			}
+			if score := c.evictionScore(v); victim == nil || score < victimScore {
+				victimKey, victim, victimScore = k, v, score
+			}
+			sampled++
+			if sampled >= EvictionSampleSize {
+				break
+			}
 		}
 
-		delete(c.items, cand.Key)
-		c.totalSize -= cand.Size
+		delete(s.items, victimKey)
+		s.totalSize -= victim.Size
+		c.evictions.Add(1)
+	}
+}
+
+// evictionScore ranks an item for the active policy; lower scores are evicted first.
+func (c *MemoryCache) evictionScore(item *Item) int64 {
+	switch c.policy {
+	case PolicyLRU:
+		return item.lastAccess.Load()
+	case PolicyLFU:
+		return int64(item.hits.Load())
+	case PolicyFIFO:
+		return item.CreatedAt.UnixNano()
+	default: // PolicyTTL
+		return item.ExpiresAt.UnixNano()
 	}
 }
 
 // startGC is a background worker that removes expired items.
+// Shards are swept one at a time so only 1/ShardCount of the keys is blocked at once.
 func (c *MemoryCache) startGC() {
 	ticker := time.NewTicker(GCInterval)
 	for range ticker.C {
-		c.Lock() // Write Lock
-		if c.items == nil || len(c.items) == 0 {
-			c.Unlock()
-			continue
-		}
 		now := time.Now()
 		removedCount := 0
 		removedBytes := int64(0)
 
-		for k, v := range c.items {
-			if now.After(v.ExpiresAt) {
-				delete(c.items, k)
-				c.totalSize -= v.Size
-				removedBytes += v.Size
-				removedCount++
+		for _, s := range c.shards {
+			s.Lock() // Write Lock
+			for k, v := range s.items {
+				if now.After(v.ExpiresAt) {
+					delete(s.items, k)
+					s.totalSize -= v.Size
+					removedBytes += v.Size
+					removedCount++
+				}
 			}
+			s.Unlock()
 		}
-		c.Unlock()
 
 		if removedCount > 0 {
 			log.Printf("[CACHE] GC: Cleaned %d items (%s freed)", removedCount, utils.FormatBytes(removedBytes))
@@ -229,20 +365,40 @@ func (c *MemoryCache) startGC() {
 	}
 }
 
+// usage sums item count and bytes across shards.
+func (c *MemoryCache) usage() (count int, used int64) {
+	for _, s := range c.shards {
+		s.RLock()
+		count += len(s.items)
+		used += s.totalSize
+		s.RUnlock()
+	}
+	return count, used
+}
+
+// Stats returns the current item count, byte usage and lifetime hit/miss/eviction counters.
+// A disabled cache reports zeros (Get doesn't count lookups in pass-through mode).
+func (c *MemoryCache) Stats() Stats {
+	count, used := c.usage()
+	return Stats{
+		Count:     count,
+		TotalSize: used,
+		MaxSize:   c.maxSize,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
 // startMonitor logs cache statistics periodically.
 func (c *MemoryCache) startMonitor() {
 	ticker := time.NewTicker(MonitorInterval)
 	for range ticker.C {
-		c.RLock()
-		if c.items == nil || len(c.items) == 0 {
-			c.RUnlock()
+		count, used := c.usage()
+		if count == 0 {
 			continue
 		}
-
-		count := len(c.items)
-		used := c.totalSize
 		max := c.maxSize
-		c.RUnlock()
 
 		percent := 0.0
 		if max > 0 {
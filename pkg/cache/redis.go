@@ -0,0 +1,331 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"octa/pkg/logger"
+)
+
+const (
+	// RedisKeyPrefix namespaces Octa's keys so a Redis instance can be shared with other apps.
+	RedisKeyPrefix = "octa:"
+
+	// RedisOpTimeout bounds every cache call. A slow Redis must cost a miss, not a stalled request.
+	RedisOpTimeout = 250 * time.Millisecond
+
+	// RedisScanCount is the SCAN batch size used by DeletePrefix.
+	RedisScanCount = 500
+
+	// RedisScanTimeout bounds the keyspace walk of a folder-prefix DeletePrefix.
+	RedisScanTimeout = 5 * time.Second
+
+	// RedisIndexPrefix holds one SET per seed listing its variant keys (see variantHead), so
+	// invalidating a seed reads its set instead of scanning the whole keyspace.
+	RedisIndexPrefix = RedisKeyPrefix + "idx:"
+
+	// RedisErrorLogInterval throttles error logs while Redis is down (every request would log otherwise).
+	RedisErrorLogInterval = time.Minute
+)
+
+// RedisCache stores items in Redis, shared by every Octa instance behind a load balancer.
+// Uploads, deletes and key edits invalidate the shared keys, so all instances see them at once.
+// Expiry and memory limits are Redis' own (TTL per key, maxmemory-policy); cache.max_capacity
+// and cache.eviction_policy only apply to the memory backend.
+//
+// Variant keys ("gen:bob?<options>") are also recorded in an index set per seed ("idx:gen:bob?"),
+// which DeletePrefix reads for seed prefixes. Other prefixes (folders) fall back to one SCAN.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+
+	// Counters of this instance's lookups; Redis has no per-client view of them.
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	lastErrLog atomic.Int64 // UnixNano of the last logged error
+}
+
+// NewRedis connects to redisURL (redis:// or rediss://). An unreachable server is logged but
+// not fatal: the client reconnects on its own and lookups are misses until it answers.
+func NewRedis(redisURL string, ttl time.Duration) *RedisCache {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		// Validate rejects bad URLs at startup; this only guards direct callers.
+		logger.LogFatal("Invalid cache.redis_url: %v", err)
+	}
+	opts.ContextTimeoutEnabled = true
+	opts.MaxRetries = -1 // A miss is cheaper than retrying with backoff while Redis is down
+
+	c := &RedisCache{client: redis.NewClient(opts), ttl: ttl}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		logger.LogError("Redis cache at %s is unreachable, serving without cache until it answers: %v", opts.Addr, err)
+	} else {
+		logger.LogInfo("Redis Cache Initialized: %s (db %d), TTL: %s", opts.Addr, opts.DB, ttl)
+	}
+	return c
+}
+
+// Get retrieves an item; errors (timeouts, connection loss) count as misses.
+func (c *RedisCache) Get(key string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), RedisOpTimeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, RedisKeyPrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.logError("GET", err)
+		}
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return data, true
+}
+
+// Set stores a value with the configured TTL.
+func (c *RedisCache) Set(key string, data []byte) {
+	c.SetWithTTL(key, data, c.ttl)
+}
+
+// SetWithTTL stores a value that expires after ttl (the configured TTL when ttl <= 0).
+// Items over MaxItemSize are skipped, as in the memory backend.
+func (c *RedisCache) SetWithTTL(key string, data []byte, ttl time.Duration) {
+	if len(data) > MaxItemSize {
+		return
+	}
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RedisOpTimeout)
+	defer cancel()
+
+	head, ok := variantHead(key)
+	if !ok {
+		if err := c.client.Set(ctx, RedisKeyPrefix+key, data, ttl).Err(); err != nil {
+			c.logError("SET", err)
+		}
+		return
+	}
+
+	// MULTI: a variant is never stored without its index entry, or invalidation would miss it.
+	// The index outlives its members; a variant that expired first is a harmless UNLINK later.
+	idx := RedisIndexPrefix + head
+	_, err := c.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, RedisKeyPrefix+key, data, ttl)
+		p.SAdd(ctx, idx, RedisKeyPrefix+key)
+		p.Expire(ctx, idx, max(ttl, c.ttl))
+		return nil
+	})
+	if err != nil {
+		c.logError("SET", err)
+	}
+}
+
+// Delete removes a key for every instance.
+func (c *RedisCache) Delete(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), RedisOpTimeout)
+	defer cancel()
+
+	if err := c.client.Unlink(ctx, RedisKeyPrefix+key).Err(); err != nil {
+		c.logError("UNLINK", err)
+	}
+}
+
+// DeletePrefix removes the keys under each prefix. Seed prefixes ("gen:bob?") are looked up in
+// their index sets; the rest share a single SCAN (never KEYS, which blocks Redis) that tests every
+// prefix. Like the memory backend, it is meant for rare invalidations.
+// Failures are always logged: an incomplete invalidation serves stale entries until they expire.
+func (c *RedisCache) DeletePrefix(prefixes ...string) int {
+	var seeds, others []string
+	for _, p := range prefixes {
+		if head, ok := variantHead(p); ok && head == p {
+			seeds = append(seeds, p)
+		} else {
+			others = append(others, p)
+		}
+	}
+
+	removed := 0
+	var failures []string
+	if len(seeds) > 0 {
+		n, err := c.deleteSeeds(seeds)
+		removed += n
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%d seed prefixes: %v", len(seeds), err))
+		}
+	}
+	if len(others) > 0 {
+		n, err := c.deleteScan(others)
+		removed += n
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%d prefixes: %v", len(others), err))
+		}
+	}
+
+	if len(failures) > 0 {
+		logger.LogError("Redis cache invalidation incomplete (%d keys removed), stale entries may be served until they expire: %v",
+			removed, strings.Join(failures, "; "))
+	}
+	return removed
+}
+
+// deleteSeeds unlinks every variant listed in the seeds' index sets, and the sets themselves.
+func (c *RedisCache) deleteSeeds(seeds []string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 4*RedisOpTimeout)
+	defer cancel()
+
+	members := make([]*redis.StringSliceCmd, len(seeds))
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, seed := range seeds {
+			members[i] = p.SMembers(ctx, RedisIndexPrefix+seed)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("SMEMBERS: %w", err)
+	}
+
+	removed := 0
+	for start := 0; start < len(seeds); start += RedisScanCount {
+		end := min(start+RedisScanCount, len(seeds))
+
+		var keys []string
+		indexes := 0
+		for i := start; i < end; i++ {
+			if vals := members[i].Val(); len(vals) > 0 { // Redis drops empty sets, so no members = no index
+				keys = append(keys, vals...)
+				keys = append(keys, RedisIndexPrefix+seeds[i])
+				indexes++
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		n, err := c.client.Unlink(ctx, keys...).Result()
+		if err != nil {
+			return removed, fmt.Errorf("UNLINK: %w", err)
+		}
+		removed += max(0, int(n)-indexes)
+	}
+	return removed, nil
+}
+
+// deleteScan walks the namespace once and unlinks keys (and index sets) under any of the prefixes.
+func (c *RedisCache) deleteScan(prefixes []string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RedisScanTimeout)
+	defer cancel()
+
+	removed := 0
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, RedisKeyPrefix+"*", RedisScanCount).Result()
+		if err != nil {
+			return removed, fmt.Errorf("SCAN: %w", err)
+		}
+
+		var doomed []string
+		indexes := 0
+		for _, k := range keys {
+			name, isIndex := strings.CutPrefix(k, RedisIndexPrefix)
+			if !isIndex {
+				name = strings.TrimPrefix(k, RedisKeyPrefix)
+			}
+			if hasAnyPrefix(name, prefixes) {
+				doomed = append(doomed, k)
+				if isIndex {
+					indexes++
+				}
+			}
+		}
+		if len(doomed) > 0 {
+			n, err := c.client.Unlink(ctx, doomed...).Result()
+			if err != nil {
+				return removed, fmt.Errorf("UNLINK: %w", err)
+			}
+			removed += max(0, int(n)-indexes)
+		}
+
+		if cursor = next; cursor == 0 {
+			return removed, nil
+		}
+	}
+}
+
+// Stats combines this instance's hit/miss counters with the server's key count, memory use
+// and evictions. The key count is DBSIZE, so it includes the index sets and other apps' keys
+// in a shared database.
+func (c *RedisCache) Stats() Stats {
+	st := Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*RedisOpTimeout)
+	defer cancel()
+
+	if n, err := c.client.DBSize(ctx).Result(); err == nil {
+		st.Count = int(n)
+	}
+	if info, err := c.client.Info(ctx, "memory", "stats").Result(); err == nil {
+		fields := parseRedisInfo(info)
+		st.TotalSize, _ = strconv.ParseInt(fields["used_memory"], 10, 64)
+		st.MaxSize, _ = strconv.ParseInt(fields["maxmemory"], 10, 64)
+		st.Evictions, _ = strconv.ParseUint(fields["evicted_keys"], 10, 64)
+	}
+	return st
+}
+
+// logError logs a failed call at most once per RedisErrorLogInterval.
+func (c *RedisCache) logError(op string, err error) {
+	now := time.Now().UnixNano()
+	last := c.lastErrLog.Load()
+	if now-last < int64(RedisErrorLogInterval) || !c.lastErrLog.CompareAndSwap(last, now) {
+		return
+	}
+	logger.LogWarn("Redis cache %s failed (treated as a miss): %v", op, err)
+}
+
+// parseRedisInfo turns INFO output ("key:value" lines, "# Section" headers) into a map.
+func parseRedisInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// variantSep ends the seed part of a variant key: "gen:bob?<options>" (see the handlers' cacheKeyPrefix).
+const variantSep = "?"
+
+// variantHead returns the seed part of a variant key, through the separator ("gen:bob?").
+func variantHead(key string) (string, bool) {
+	i := strings.Index(key, variantSep)
+	if i < 0 {
+		return "", false
+	}
+	return key[:i+len(variantSep)], true
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package styles
+
+import (
+	"crypto/md5"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"octa/pkg/utils"
+)
+
+// IdenticonGrid is the number of cells per side of theme=identicon (GitHub-style).
+const IdenticonGrid = 5
+
+// identiconColumns is how many columns are drawn; the rest mirror them (5 -> 3 unique).
+const identiconColumns = (IdenticonGrid + 1) / 2
+
+// identiconCells derives the on/off pattern of the left columns from an md5 of the seed.
+// Bit row*identiconColumns+col is set when that cell (and its mirror) is filled.
+func identiconCells(seed string) uint32 {
+	sum := md5.Sum([]byte(seed))
+	var cells uint32
+	for i := 0; i < IdenticonGrid*identiconColumns; i++ {
+		if sum[i/8]>>(i%8)&1 == 1 {
+			cells |= 1 << i
+		}
+	}
+	return cells
+}
+
+// identiconRects lists the filled cells in pixels, mirrored around the middle column.
+// The grid sits in the largest square inside the shape (and ring), with half a cell of
+// padding, so rounded corners and circles never clip it.
+func identiconRects(opts Options) []image.Rectangle {
+	size := float64(opts.Size)
+	radius := size * opts.Rounded
+	inset := radius*(1-1/math.Sqrt2) + size*opts.Ring
+
+	cell := (size - 2*inset) / (IdenticonGrid + 1)
+	origin := inset + cell/2
+	edge := func(i int) int { return int(math.Round(origin + float64(i)*cell)) }
+
+	var rects []image.Rectangle
+	for row := 0; row < IdenticonGrid; row++ {
+		for col := 0; col < IdenticonGrid; col++ {
+			src := col
+			if col >= identiconColumns {
+				src = IdenticonGrid - 1 - col
+			}
+			if opts.Cells&(1<<(row*identiconColumns+src)) == 0 {
+				continue
+			}
+			rects = append(rects, image.Rect(edge(col), edge(row), edge(col+1), edge(row+1)))
+		}
+	}
+	return rects
+}
+
+// drawIdenticon fills the pattern over the already painted background.
+func drawIdenticon(img *image.RGBA, opts Options) {
+	c := opts.Text
+	for _, r := range identiconRects(opts) {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.SetRGBA(x, y, blendOver(img.RGBAAt(x, y), c))
+			}
+		}
+	}
+}
+
+// blendOver composites premultiplied src over dst.
+func blendOver(dst, src color.RGBA) color.RGBA {
+	if src.A == 255 {
+		return src
+	}
+	k := 1 - float64(src.A)/255
+	return color.RGBA{
+		src.R + uint8(float64(dst.R)*k), src.G + uint8(float64(dst.G)*k),
+		src.B + uint8(float64(dst.B)*k), src.A + uint8(float64(dst.A)*k),
+	}
+}
+
+// identiconSVG is the background shape (via GenerateSVG without text) plus one <rect> per cell.
+func identiconSVG(opts Options) string {
+	radius := float64(opts.Size) * opts.Rounded
+	ring := float64(opts.Size) * opts.Ring
+	svg := utils.GenerateSVG(opts.Size, "", opts.BG1, opts.BG2, "", int(radius), opts.Text, "color", false, ring, opts.RingColor, "", 0)
+
+	var cells strings.Builder
+	fmt.Fprintf(&cells, "\t<g %s>\n", utils.SVGPaint("fill", opts.Text))
+	for _, r := range identiconRects(opts) {
+		fmt.Fprintf(&cells, "\t\t<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" />\n", r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+	}
+	cells.WriteString("\t</g>\n")
+
+	return strings.Replace(svg, "</svg>", cells.String()+"</svg>", 1)
+}
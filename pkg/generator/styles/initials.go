@@ -2,30 +2,95 @@ package styles
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash/fnv"
 	"image"
 	"image/color"
 	"image/png"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 
 	"octa/internal/config"
+	"octa/pkg/logger"
 	"octa/pkg/utils"
 )
 
 const DefaultAvatarSize = 360
 
+// IcoSizes are the resolutions packed into format=ico (favicon use).
+var IcoSizes = []int{16, 32, 48}
+
+// Options is the fully resolved input of one render. Every field is already
+// clamped/normalized, so equal Options always produce identical bytes.
+type Options struct {
+	Format    string // "png", "webp", "avif", "svg", "ico" or "pdf"
+	Style     string // "color", "gradient", "soft" or "identicon"
+	Initials  string
+	Font      string  // Registered font name (image.fonts), "" = default
+	Weight    int     // SVG only: CSS font-weight of <text> (0 = image.font_weight)
+	Size      int     // Clamped & snapped (16-1024)
+	Rounded   float64 // Corner radius as a fraction of Size (0-0.5)
+	BG1, BG2  color.RGBA
+	Text      color.RGBA
+	Cells     uint32  // Identicon only: filled cells of the left columns (see identiconCells)
+	EmbedFont bool    // SVG only: initials as glyph outlines
+	Ring      float64 // Border width inside the outline as a fraction of Size (0-MaxRing)
+	RingColor color.RGBA
+}
+
+// MaxRing caps ?ring at a quarter of the avatar so the initials keep their room.
+const MaxRing = 0.25
+
+// MimeType returns the Content-Type of the rendered output.
+func (o Options) MimeType() string {
+	switch o.Format {
+	case "svg":
+		return "image/svg+xml"
+	case "ico":
+		return "image/x-icon"
+	case "pdf":
+		return "application/pdf"
+	case "webp":
+		return "image/webp"
+	case "avif":
+		return "image/avif"
+	}
+	return "image/png"
+}
+
+// Key is a content address for the render: two requests resolving to the same
+// options (e.g. ?size=9999 and ?size=1024) share one key.
+func (o Options) Key() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%d|%.4f|%v|%v|%v|%t|%.4f|%v|%d|%s|%d",
+		o.Format, o.Style, o.Initials, o.Size, o.Rounded, o.BG1, o.BG2, o.Text, o.EmbedFont, o.Ring, o.RingColor, o.Cells, o.Font, o.Weight)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
 // ============================================================================
 // 1. YENİ CORE FONKSİYON (MOTOR) ⚙️
 // Sadece veri üretir, HTTP bilmez. Cache ve eski fonksiyon bunu çağırır.
 // ============================================================================
 func GenerateImageBytes(name string, query url.Values) ([]byte, string, error) {
+	return RenderAvatar(ResolveOptions(name, query))
+}
+
+// ResolveOptions turns a seed and raw query into normalized render Options.
+func ResolveOptions(name string, query url.Values) Options {
 
 	// Format
 	format := "png"
-	if f := query.Get("format"); f == "svg" || f == "png" {
+	if f := query.Get("format"); f == "svg" || f == "png" || f == "ico" || f == "pdf" {
+		format = f
+	} else if f == "webp" && webpAvailable() {
+		format = f
+	} else if f == "avif" && config.AppConfig.Image.AllowAVIF {
 		format = f
 	} else if t := query.Get("type"); t == "svg" {
 		format = "svg"
@@ -46,9 +111,13 @@ func GenerateImageBytes(name string, query url.Values) ([]byte, string, error) {
 		style = at 
 	}
 
-	if style != "gradient" && style != "soft" {
+	if style != "gradient" && style != "soft" && style != "identicon" {
 		style = "color"
 	}
+	identicon := style == "identicon"
+	if identicon && format == "pdf" {
+		format = "svg" // The PDF writer only draws initials; keep identicons vector
+	}
 
 	// name
 	initials := query.Get("initials")
@@ -59,24 +128,40 @@ func GenerateImageBytes(name string, query url.Values) ([]byte, string, error) {
 		}
 		initials = utils.GetInitials(targetName)
 	}
+	// Font: a name from image.fonts; unknown names use the default font (and share its cache key)
+	fontName := query.Get("font")
+	if !utils.FontRegistered(fontName) {
+		fontName = ""
+	}
+
+	// Weight: the closest registered variant of the default font (named fonts have one weight).
+	// SVG <text> gets the exact CSS weight; other formats only differ by the chosen file.
+	weight := parseWeight(query.Get("weight"))
+	if weight > 0 && fontName == "" {
+		fontName = utils.FontForWeight(weight)
+	}
+	if format != "svg" {
+		weight = 0
+	}
+
+	// Emoji or symbol-only seeds: never draw a missing-glyph box
+	clientText := format == "svg" && query.Get("embedFont") != "true" // The browser draws the text
+	initials = drawableInitials(initials, name, query.Get("fallback"), fontName, clientText)
 
 	// size
 	size := config.AppConfig.Image.DefaultSize
 	if size == 0 {
 		size = DefaultAvatarSize
 	}
-	if sVal := query.Get("size"); sVal == "" {
+	sVal := query.Get("size")
+	if sVal == "" {
 		sVal = query.Get("w")
-	} else {
-		if s, err := strconv.Atoi(sVal); err == nil {
-			if s > 1024 {
-				size = 1024
-			} else if s < 16 {
-				size = 16
-			} else {
-				size = s
-			}
-		}
+	}
+	if s, err := strconv.Atoi(sVal); err == nil {
+		size = SnapSize(s)
+	}
+	if format == "ico" {
+		size = IcoSizes[len(IcoSizes)-1] // Fixed set of sizes: ?size must not split the cache
 	}
 
 	// Calculate Color
@@ -94,6 +179,12 @@ func GenerateImageBytes(name string, query url.Values) ([]byte, string, error) {
 		pair := utils.MakeSoft(seed)
 		bg1, txtColor = pair.Background, pair.Text
 		bg2 = utils.SoftDarken(bg1, 0.05)
+	case "identicon":
+		// Palette color for the cells on a light soft variant of it
+		cell := utils.GetColorFromPalette(name, palette)
+		bg1 = utils.MakeSoft(cell).Background
+		bg2 = bg1
+		txtColor = cell
 	case "gradient":
 		bg1, bg2 = utils.GenerateGradient(name, palette)
 		txtColor = utils.DetermineTextColorAdvanced(bg1, bg2, "gradient", "")
@@ -105,7 +196,14 @@ func GenerateImageBytes(name string, query url.Values) ([]byte, string, error) {
 
 	// Override
 	userHasBg := false
-	if bgOv := query.Get("bg"); bgOv != "" {
+	transparentBg := TransparentBackground(query.Get("bg"))
+	if transparentBg {
+		// Initials only: tint them with the seed's color (soft already pairs a darker text color)
+		if style != "soft" && !identicon {
+			txtColor = bg1
+		}
+		bg1, bg2 = color.RGBA{}, color.RGBA{}
+	} else if bgOv := query.Get("bg"); bgOv != "" {
 		if c, err := utils.ParseColor(bgOv); err == nil {
 			bg1, bg2 = c, c
 			userHasBg = true
@@ -113,40 +211,212 @@ func GenerateImageBytes(name string, query url.Values) ([]byte, string, error) {
 	}
 	if txtOv := query.Get("color"); txtOv != "" {
 		txtColor = utils.DetermineTextColorAdvanced(bg1, bg2, style, txtOv)
-	} else if userHasBg {
-		txtColor = utils.DetermineTextColorAdvanced(bg1, bg2, "custom", "")
+	} else {
+		if userHasBg && !identicon {
+			txtColor = utils.DetermineTextColorAdvanced(bg1, bg2, "custom", "")
+		}
+		// Guarantee AA contrast for auto-picked text (only wide gradients ever need adjusting).
+		// An explicit ?color is the user's choice and is left alone, and so is a transparent background.
+		// Identicon cells are not text and keep their palette color.
+		if !transparentBg && !identicon {
+			bg1 = utils.EnsureContrast(bg1, txtColor)
+			bg2 = utils.EnsureContrast(bg2, txtColor)
+		}
 	}
 
-	// Rounded
-	var radius float64
+	// Rounded: "true" is a subtle 1/16 radius, a number is a percentage capped at 50 (circle)
+	var rounded float64
 	if rVal := query.Get("rounded"); rVal == "true" {
-		radius = float64(size) / 16.0
+		rounded = 1.0 / 16.0
 	} else if rVal != "" {
-		if v, err := strconv.Atoi(rVal); err == nil {
+		if v, err := strconv.Atoi(rVal); err == nil && v > 0 {
 			if v > 50 {
 				v = 50
 			}
-			radius = (float64(size) / 2.0) * (float64(v) / 100.0) * 2
+			rounded = float64(v) / 100.0
+		}
+	}
+	// Shape: a circle is a 50% radius, so shape=circle and rounded=50 share one render
+	if query.Get("shape") == "circle" {
+		rounded = 0.5
+	}
+
+	// Ring: width in px of the requested size, stored relative so ICO frames scale it.
+	// Default color is a darker shade of the background (the text color when there is none).
+	var ring float64
+	var ringColor color.RGBA
+	if px, err := strconv.Atoi(query.Get("ring")); err == nil && px > 0 {
+		ring = math.Min(float64(px)/float64(size), MaxRing)
+		if c, err := utils.ParseColor(query.Get("ringColor")); err == nil {
+			ringColor = c
+		} else if transparentBg {
+			ringColor = color.RGBAModel.Convert(txtColor).(color.RGBA)
+		} else {
+			ringColor = utils.SoftDarken(bg1, 0.15)
 		}
 	}
 
+	// Font embedding: only meaningful for SVG, so PNG variants keep one cache key
+	embedFont := format == "svg" && query.Get("embedFont") == "true"
+
+	// Identicon: the pattern replaces the initials, so initials/iName must not split the cache
+	var cells uint32
+	if identicon {
+		initials, embedFont, fontName, weight = "", false, "", 0
+		cells = identiconCells(name)
+	}
+
+	return Options{
+		Format:    format,
+		Style:     style,
+		Initials:  initials,
+		Font:      fontName,
+		Weight:    weight,
+		Size:      size,
+		Rounded:   rounded,
+		BG1:       bg1,
+		BG2:       bg2,
+		Text:      color.RGBAModel.Convert(txtColor).(color.RGBA),
+		Cells:     cells,
+		EmbedFont: embedFont,
+		Ring:      ring,
+		RingColor: ringColor,
+	}
+}
+
+// parseWeight reads ?weight as a name (light, regular, medium, semibold, bold) or a CSS
+// number (100-900, rounded to hundreds). 0 means not set or invalid.
+func parseWeight(v string) int {
+	if w, ok := config.FontWeightValues[strings.ToLower(v)]; ok {
+		return w
+	}
+	if w, err := strconv.Atoi(v); err == nil && w >= 100 && w <= 900 {
+		return (w + 50) / 100 * 100
+	}
+	return 0
+}
+
+// svgWeight is the font-weight of SVG <text>: the requested one, else the default font's.
+func svgWeight(weight int) int {
+	if weight > 0 {
+		return weight
+	}
+	if w := config.AppConfig.Image.FontWeight; w > 0 {
+		return w
+	}
+	return 600
+}
+
+// fallbackEmoji and fallbackSymbols are the fallback=emoji / fallback=symbol choices.
+// Symbols are ones Inter has; drawableInitials still checks them against the loaded font.
+var (
+	fallbackEmoji   = []string{"🦊", "🐼", "🐨", "🐯", "🦁", "🐸", "🐙", "🦉", "🐧", "🐢", "🦄", "🐝"}
+	fallbackSymbols = []string{"★", "●", "■", "▲", "◆", "♥", "❖", "☀", "▶", "∞"}
+)
+
+// drawableInitials drops runes the font cannot draw ("🦊 Ada" -> "A"). When nothing is left,
+// the seed picks a stand-in: mode "emoji" (only drawn where the client renders SVG text,
+// otherwise it acts like "symbol"), "symbol", or "letter" (default, A-Z).
+func drawableInitials(initials, seed, mode, fontName string, clientText bool) string {
+	if initials == "" || utils.CanDraw(initials, fontName) {
+		return initials
+	}
+
+	var kept strings.Builder
+	for _, r := range initials {
+		if utils.CanDraw(string(r), fontName) {
+			kept.WriteRune(r)
+		}
+	}
+	if kept.Len() > 0 {
+		return kept.String()
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	n := int(h.Sum32() & 0x7fffffff) // Non-negative on 32-bit too
+
+	if mode == "emoji" {
+		if e := fallbackEmoji[n%len(fallbackEmoji)]; clientText || utils.CanDraw(e, fontName) {
+			return e
+		}
+		mode = "symbol"
+	}
+	if mode == "symbol" {
+		for i := range fallbackSymbols {
+			if sym := fallbackSymbols[(n+i)%len(fallbackSymbols)]; utils.CanDraw(sym, fontName) {
+				return sym
+			}
+		}
+	}
+	return string(rune('A' + n%26))
+}
+
+// lerpRGBA mixes premultiplied colors: t=0 is a, t=1 is b. Toward transparent it fades the alpha.
+func lerpRGBA(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 { return uint8(float64(x)*(1-t) + float64(y)*t + 0.5) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// TransparentBackground reports whether a ?bg value asks for initials on a transparent background.
+func TransparentBackground(bg string) bool {
+	return bg == "transparent" || bg == "none"
+}
+
+// RenderAvatar draws the avatar described by opts. It performs no parsing.
+func RenderAvatar(opts Options) ([]byte, string, error) {
+	size := opts.Size
+	style := opts.Style
+	initials := opts.Initials
+	bg1, bg2 := opts.BG1, opts.BG2
+	txtColor := opts.Text
+	radius := float64(size) * opts.Rounded
+	ring := float64(size) * opts.Ring
 
 	// SVG
-	if format == "svg" {
-		svgContent := utils.GenerateSVG(size, name, bg1, bg2, initials, int(radius), txtColor, style)
-		return []byte(svgContent), "image/svg+xml", nil
+	if opts.Format == "svg" && style == "identicon" {
+		return []byte(identiconSVG(opts)), opts.MimeType(), nil
+	}
+	if opts.Format == "svg" {
+		svgContent := utils.GenerateSVG(size, initials, bg1, bg2, initials, int(radius), txtColor, style, opts.EmbedFont, ring, opts.RingColor, opts.Font, svgWeight(opts.Weight))
+		return []byte(svgContent), opts.MimeType(), nil
+	}
+
+	// PDF: vector page of size x size points
+	if opts.Format == "pdf" {
+		return utils.GeneratePDF(size, bg1, bg2, initials, int(radius), txtColor, style, ring, opts.RingColor, opts.Font), opts.MimeType(), nil
+	}
+
+	// ICO: every resolution is rendered natively, so text and corners stay crisp at 16px
+	if opts.Format == "ico" {
+		frames := make([][]byte, 0, len(IcoSizes))
+		for _, s := range IcoSizes {
+			frameOpts := opts
+			frameOpts.Format, frameOpts.Size = "png", s
+			frame, _, err := RenderAvatar(frameOpts)
+			if err != nil {
+				return nil, "", err
+			}
+			frames = append(frames, frame)
+		}
+		data, err := utils.EncodeICO(frames)
+		if err != nil {
+			return nil, "", fmt.Errorf("encode error: %v", err)
+		}
+		return data, opts.MimeType(), nil
 	}
 
 	// PNG (Pixel Perfect)
 	img := image.NewRGBA(image.Rect(0, 0, size, size))
 	fSize := float64(size)
-	rSq := radius * radius
-
 	for y := 0; y < size; y++ {
 		fy := float64(y) + 0.5
 		for x := 0; x < size; x++ {
+			fx := float64(x) + 0.5
+
+			// edge: distance from the pixel center to the outline, positive inside
+			edge := math.Min(math.Min(fx, fy), math.Min(fSize-fx, fSize-fy))
 			if radius > 0 {
-				fx := float64(x) + 0.5
 				dx, dy := 0.0, 0.0
 				isCorner := false
 				if fx < radius && fy < radius {
@@ -158,25 +428,57 @@ func GenerateImageBytes(name string, query url.Values) ([]byte, string, error) {
 				} else if fx > fSize-radius && fy > fSize-radius {
 					dx, dy, isCorner = fx-(fSize-radius), fy-(fSize-radius), true
 				}
-				if isCorner && (dx*dx+dy*dy > rSq) {
-					continue
+				if isCorner {
+					edge = radius - math.Hypot(dx, dy)
 				}
 			}
+			if edge <= -0.5 {
+				continue
+			}
 
-			if bg1 == bg2 {
-				img.SetRGBA(x, y, bg1)
-			} else {
+			c := bg1
+			if bg1 != bg2 {
 				ratio := (float64(x) + float64(y)) / (2 * fSize)
 				r := uint8(float64(bg1.R)*(1-ratio) + float64(bg2.R)*ratio)
 				g := uint8(float64(bg1.G)*(1-ratio) + float64(bg2.G)*ratio)
 				b := uint8(float64(bg1.B)*(1-ratio) + float64(bg2.B)*ratio)
-				img.SetRGBA(x, y, color.RGBA{r, g, b, 255})
+				a := uint8(float64(bg1.A)*(1-ratio) + float64(bg2.A)*ratio)
+				c = color.RGBA{r, g, b, a}
+			}
+			// Ring: everything closer than ring px to the outline, inner edge anti-aliased too
+			if ring > 0 {
+				if t := ring - edge + 0.5; t > 0 {
+					c = lerpRGBA(c, opts.RingColor, math.Min(t, 1))
+				}
 			}
+			// Edge coverage (anti-aliasing): fractional within half a pixel of the outline
+			if coverage := edge + 0.5; coverage < 1 {
+				c = lerpRGBA(color.RGBA{}, c, coverage)
+			}
+			img.SetRGBA(x, y, c)
 		}
 	}
 
-	if initials != "" {
-		utils.DrawText(img, initials, txtColor, size)
+	if style == "identicon" {
+		drawIdenticon(img, opts)
+	} else if initials != "" {
+		utils.DrawText(img, initials, txtColor, size, opts.Font)
+	}
+
+	if opts.Format == "webp" {
+		data, err := utils.EncodeWebP(img, encodeQuality())
+		if err != nil {
+			return nil, "", fmt.Errorf("encode error: %v", err)
+		}
+		return data, opts.MimeType(), nil
+	}
+
+	if opts.Format == "avif" {
+		data, err := utils.EncodeAVIF(img, encodeQuality())
+		if err != nil {
+			return nil, "", fmt.Errorf("encode error: %v", err)
+		}
+		return data, opts.MimeType(), nil
 	}
 
 	var buf bytes.Buffer
@@ -184,7 +486,49 @@ func GenerateImageBytes(name string, query url.Values) ([]byte, string, error) {
 		return nil, "", fmt.Errorf("encode error: %v", err)
 	}
 
-	return buf.Bytes(), "image/png", nil
+	return buf.Bytes(), opts.MimeType(), nil
+}
+
+var (
+	webpOK   bool
+	webpOnce sync.Once
+)
+
+// webpAvailable probes the WebP encoder once. When it cannot run, format=webp
+// resolves to PNG so the cache key and Content-Type always match the bytes.
+func webpAvailable() bool {
+	webpOnce.Do(func() {
+		_, err := utils.EncodeWebP(image.NewRGBA(image.Rect(0, 0, 1, 1)), encodeQuality())
+		webpOK = err == nil
+		if !webpOK {
+			logger.LogWarn("WebP encoder unavailable, format=webp falls back to PNG: %v", err)
+		}
+	})
+	return webpOK
+}
+
+// encodeQuality is the lossy WebP/AVIF quality: image.quality (validated at startup to 1-100).
+func encodeQuality() int {
+	if q := config.AppConfig.Image.Quality; q >= 1 && q <= 100 {
+		return q
+	}
+	return 80
+}
+
+// SnapSize clamps a requested size to 16-1024 and rounds it to the configured
+// image.size_step bucket, so ?size=100 and ?size=101 share one rendered variant.
+func SnapSize(s int) int {
+	if step := config.AppConfig.Image.SizeStep; step > 1 {
+		s = ((s + step/2) / step) * step
+	}
+
+	if s > 1024 {
+		return 1024
+	}
+	if s < 16 {
+		return 16
+	}
+	return s
 }
 
 func GenerateInitialsAvatar(name string, w http.ResponseWriter, r *http.Request) {
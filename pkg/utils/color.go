@@ -6,6 +6,8 @@ import (
 	"math"
 	"strconv"
 	"strings"
+
+	"golang.org/x/image/colornames"
 )
 
 // --- YAPILAR ---
@@ -20,25 +22,10 @@ type GradientPair struct {
 	End   color.RGBA
 }
 
+// cssColors: CSS keywords missing from colornames.Map (which is the SVG 1.1 set of 147 names).
 var cssColors = map[string]color.RGBA{
-	"white":   {255, 255, 255, 255},
-	"black":   {0, 0, 0, 255},
-	"red":     {255, 0, 0, 255},
-	"green":   {0, 128, 0, 255},
-	"blue":    {0, 0, 255, 255},
-	"cyan":    {0, 255, 255, 255},
-	"magenta": {255, 0, 255, 255},
-	"yellow":  {255, 255, 0, 255},
-	"orange":  {255, 165, 0, 255},
-	"purple":  {128, 0, 128, 255},
-	"pink":    {255, 192, 203, 255},
-	"gray":    {128, 128, 128, 255},
-	"grey":    {128, 128, 128, 255},
-	"silver":  {192, 192, 192, 255},
-	"gold":    {255, 215, 0, 255},
-	"teal":    {0, 128, 128, 255},
-	"lime":    {0, 255, 0, 255},
-	"navy":    {0, 0, 128, 255},
+	"rebeccapurple": {102, 51, 153, 255},
+	"transparent":   {0, 0, 0, 0},
 }
 
 // ProColors: ~100 Selected Modern Colors
@@ -154,6 +141,8 @@ func SoftDarken(c color.RGBA, factor float64) color.RGBA {
 	return color.RGBA{r, g, b, 255}
 }
 
+// ParseColor accepts CSS names, hex (#RGB, #RGBA, #RRGGBB, #RRGGBBAA), rgb()/rgba(), hsl()/hsla() and hsv().
+// Alpha is returned premultiplied, as color.RGBA requires.
 func ParseColor(s string) (color.RGBA, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -164,32 +153,152 @@ func ParseColor(s string) (color.RGBA, error) {
 	if c, ok := cssColors[lowerName]; ok {
 		return c, nil
 	}
+	if c, ok := colornames.Map[lowerName]; ok {
+		return c, nil
+	}
+
+	if strings.HasPrefix(lowerName, "rgb") {
+		return parseRGBFunc(lowerName)
+	}
+	if strings.HasPrefix(lowerName, "hs") {
+		return parseHueFunc(lowerName)
+	}
 
-	c := color.RGBA{A: 255}
 	hexStr := strings.TrimPrefix(s, "#")
 
-	switch len(hexStr) {
-	case 6:
-		r, err1 := strconv.ParseUint(hexStr[0:2], 16, 8)
-		g, err2 := strconv.ParseUint(hexStr[2:4], 16, 8)
-		b, err3 := strconv.ParseUint(hexStr[4:6], 16, 8)
-		if err1 != nil || err2 != nil || err3 != nil {
-			return color.RGBA{}, errors.New("invalid hex")
+	// Expand short forms: "f80" -> "ff8800", "f808" -> "ff880088"
+	if len(hexStr) == 3 || len(hexStr) == 4 {
+		var b strings.Builder
+		for _, ch := range hexStr {
+			b.WriteRune(ch)
+			b.WriteRune(ch)
 		}
-		c.R, c.G, c.B = uint8(r), uint8(g), uint8(b)
-	case 3:
-		r, err1 := strconv.ParseUint(string(hexStr[0])+string(hexStr[0]), 16, 8)
-		g, err2 := strconv.ParseUint(string(hexStr[1])+string(hexStr[1]), 16, 8)
-		b, err3 := strconv.ParseUint(string(hexStr[2])+string(hexStr[2]), 16, 8)
-		if err1 != nil || err2 != nil || err3 != nil {
-			return color.RGBA{}, errors.New("invalid hex")
+		hexStr = b.String()
+	}
+
+	if len(hexStr) != 6 && len(hexStr) != 8 {
+		return color.RGBA{}, errors.New("invalid color format")
+	}
+
+	v, err := strconv.ParseUint(hexStr, 16, 32)
+	if err != nil {
+		return color.RGBA{}, errors.New("invalid hex")
+	}
+	if len(hexStr) == 6 {
+		v = v<<8 | 0xff
+	}
+
+	return premultiply(uint8(v>>24), uint8(v>>16), uint8(v>>8), uint8(v)), nil
+}
+
+// parseRGBFunc parses "rgb(r,g,b)" and "rgba(r,g,b,a)"; a is 0-1 or a percentage.
+func parseRGBFunc(s string) (color.RGBA, error) {
+	fn, parts, err := splitColorFunc(s)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	if fn != "rgb" && fn != "rgba" {
+		return color.RGBA{}, errors.New("invalid color format")
+	}
+
+	var rgb [3]uint8
+	for i := 0; i < 3; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 || n > 255 {
+			return color.RGBA{}, errors.New("rgb() components must be 0-255")
+		}
+		rgb[i] = uint8(n)
+	}
+
+	alpha, err := parseAlpha(parts)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+
+	return premultiply(rgb[0], rgb[1], rgb[2], alpha), nil
+}
+
+// parseHueFunc parses "hsl(h,s%,l%)", "hsla(...,a)" and "hsv(h,s%,v%)" / "hsb(...)".
+// Hue is in degrees and wraps; saturation and lightness/value are clamped to 0-100%.
+func parseHueFunc(s string) (color.RGBA, error) {
+	fn, parts, err := splitColorFunc(s)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+
+	h, err := strconv.ParseFloat(strings.TrimSuffix(parts[0], "deg"), 64)
+	if err != nil {
+		return color.RGBA{}, errors.New("hue must be a number of degrees")
+	}
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+
+	var pct [2]float64
+	for i := 0; i < 2; i++ {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(parts[i+1], "%"), 64)
+		if err != nil {
+			return color.RGBA{}, errors.New("saturation and lightness must be percentages")
 		}
-		c.R, c.G, c.B = uint8(r), uint8(g), uint8(b)
+		pct[i] = math.Max(0, math.Min(100, v)) / 100
+	}
+
+	alpha, err := parseAlpha(parts)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+
+	var r, g, b uint8
+	switch fn {
+	case "hsl", "hsla":
+		r, g, b = hslToRgb(h, pct[0], pct[1])
+	case "hsv", "hsva", "hsb", "hsba":
+		r, g, b = hsvToRgb(h, pct[0], pct[1])
 	default:
 		return color.RGBA{}, errors.New("invalid color format")
 	}
 
-	return c, nil
+	return premultiply(r, g, b, alpha), nil
+}
+
+// splitColorFunc splits "fn(a, b, c[, d])" into its lowercase name and trimmed arguments.
+func splitColorFunc(s string) (string, []string, error) {
+	open, close := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || close != len(s)-1 {
+		return "", nil, errors.New("invalid color function")
+	}
+
+	parts := strings.Split(s[open+1:close], ",")
+	if len(parts) != 3 && len(parts) != 4 {
+		return "", nil, errors.New("color functions need 3 or 4 components")
+	}
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	return strings.ToLower(strings.TrimSpace(s[:open])), parts, nil
+}
+
+// parseAlpha reads the optional 4th component: 0-1 or a percentage. Missing means opaque.
+func parseAlpha(parts []string) (uint8, error) {
+	if len(parts) < 4 {
+		return 255, nil
+	}
+
+	raw, scale := parts[3], 1.0
+	if pct, ok := strings.CutSuffix(raw, "%"); ok {
+		raw, scale = pct, 100
+	}
+	a, err := strconv.ParseFloat(raw, 64)
+	if err != nil || a < 0 || a > scale {
+		return 0, errors.New("alpha must be 0-1 or 0%-100%")
+	}
+	return uint8(math.Round(a / scale * 255)), nil
+}
+
+func premultiply(r, g, b, a uint8) color.RGBA {
+	return color.RGBAModel.Convert(color.NRGBA{r, g, b, a}).(color.RGBA)
 }
 
 func rgbToHsl(r, g, b uint8) (h, s, l float64) {
@@ -241,6 +350,15 @@ func hslToRgb(h, s, l float64) (r, g, b uint8) {
 	return uint8(rf * 255), uint8(gf * 255), uint8(bf * 255)
 }
 
+func hsvToRgb(h, s, v float64) (r, g, b uint8) {
+	l := v * (1 - s/2)
+	sl := 0.0
+	if l > 0 && l < 1 {
+		sl = (v - l) / math.Min(l, 1-l)
+	}
+	return hslToRgb(h, sl, l)
+}
+
 func hueToRgb(p, q, t float64) float64 {
 	if t < 0 {
 		t += 1
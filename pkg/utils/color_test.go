@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.RGBA
+	}{
+		// Hex
+		{"#ff8800", color.RGBA{255, 136, 0, 255}},
+		{"f80", color.RGBA{255, 136, 0, 255}},
+		{"#FF880080", color.RGBA{128, 68, 0, 128}}, // hex8, alpha premultiplied
+		{"#f808", color.RGBA{136, 72, 0, 136}},     // short hex8
+		{"#00000000", color.RGBA{0, 0, 0, 0}},
+
+		// rgb() / rgba()
+		{"rgb(255, 0, 0)", color.RGBA{255, 0, 0, 255}},
+		{"rgba(0,128,255,0.5)", color.RGBA{0, 64, 128, 128}},
+		{"RGBA(0, 0, 255, 50%)", color.RGBA{0, 0, 128, 128}},
+
+		// hsl() / hsla() / hsv()
+		{"hsl(120, 100%, 50%)", color.RGBA{0, 255, 0, 255}},
+		{"hsl(480deg, 100%, 50%)", color.RGBA{0, 255, 0, 255}}, // hue wraps
+		{"hsl(-120, 100%, 50%)", color.RGBA{0, 0, 255, 255}},
+		{"hsla(240, 100%, 50%, 0.5)", color.RGBA{0, 0, 128, 128}},
+		{"hsl(0, 0%, 100%)", color.RGBA{255, 255, 255, 255}},
+		{"hsv(0, 100%, 100%)", color.RGBA{255, 0, 0, 255}},
+
+		// Names
+		{"red", color.RGBA{255, 0, 0, 255}},
+		{" Blue ", color.RGBA{0, 0, 255, 255}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if err != nil {
+			t.Errorf("ParseColor(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseColorInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"   ",
+		"#12",
+		"#12345",
+		"#1234567",
+		"#gggggg",
+		"notacolor",
+		"rgb(256, 0, 0)",
+		"rgb(-1, 0, 0)",
+		"rgb(1, 2)",
+		"rgb(1, 2, 3, 4, 5)",
+		"rgba(0, 0, 0, 1.5)",
+		"rgba(0, 0, 0, 120%)",
+		"rgb(a, b, c)",
+		"rgb(1, 2, 3",
+		"hsl(x, 50%, 50%)",
+		"hsl(0, y%, 50%)",
+		"hsla(0, 50%, 50%, -0.1)",
+		"hsx(0, 50%, 50%)",
+	}
+
+	for _, in := range invalid {
+		if c, err := ParseColor(in); err == nil {
+			t.Errorf("ParseColor(%q) = %v, want an error", in, c)
+		}
+	}
+}
@@ -5,26 +5,39 @@ import (
 	"net/http"
 )
 
+// AllowedImageTypes lists the sniffed content types accepted for uploads.
+var AllowedImageTypes = []string{
+	"image/jpeg",
+	"image/png",
+	// "image/webp",
+}
+
 func IsImageFile(fileHeader *multipart.FileHeader) bool {
+	_, ok := DetectImageType(fileHeader)
+	return ok
+}
+
+// DetectImageType sniffs the first 512 bytes of an upload.
+// It returns the detected content type (empty if unreadable) and whether it is allowed.
+func DetectImageType(fileHeader *multipart.FileHeader) (string, bool) {
 	file, err := fileHeader.Open()
 	if err != nil {
-		return false
+		return "", false
 	}
 	defer file.Close()
 
 	buff := make([]byte, 512)
-	if _, err := file.Read(buff); err != nil {
-		return false
+	n, _ := file.Read(buff)
+	if n == 0 {
+		return "", false
 	}
 
-	contentType := http.DetectContentType(buff)
+	contentType := http.DetectContentType(buff[:n])
 
-	allowed := map[string]bool{
-		"image/jpeg": true,
-		"image/jpg":  true,
-		"image/png":  true,
-		// "image/webp": true,
+	for _, t := range AllowedImageTypes {
+		if contentType == t {
+			return contentType, true
+		}
 	}
-
-	return allowed[contentType]
+	return contentType, false
 }
@@ -5,42 +5,162 @@ import (
 		"octa/pkg/logger"
 
 	"os"
+	"strings"
 	"sync"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
 )
 
 var (
-	parsedFont *opentype.Font
-	initMu     sync.Mutex
+	parsedFont *opentype.Font // Default font: image.font_path
+	fontsMu    sync.RWMutex
+	fonts      = map[string]*opentype.Font{} // Named fonts from image.fonts (?font=<name>)
 )
 
-
+// InitFonts parses the default font, used for initials without ?font and for text watermarks.
 func InitFonts(fontPath string) error {
-	initMu.Lock()
-	defer initMu.Unlock()
-	if parsedFont != nil {
-		return nil
+	f, err := parseFontFile(fontPath)
+	if err != nil {
+		return err
+	}
+	fontsMu.Lock()
+	parsedFont = f
+	fontsMu.Unlock()
+	return nil
+}
+
+// RegisterFont parses a font file once and makes it selectable by name (?font=<name>).
+func RegisterFont(name, fontPath string) error {
+	f, err := parseFontFile(fontPath)
+	if err != nil {
+		return err
+	}
+	fontsMu.Lock()
+	fonts[name] = f
+	fontsMu.Unlock()
+	return nil
+}
+
+// defaultFontWeight is the CSS weight of the default font (image.font_weight).
+var defaultFontWeight = 600
+
+// SetDefaultFontWeight records the weight of the font passed to InitFonts.
+func SetDefaultFontWeight(weight int) {
+	fontsMu.Lock()
+	defaultFontWeight = weight
+	fontsMu.Unlock()
+}
+
+// RegisterFontWeight adds a weight variant of the default font (?weight=bold).
+// Variants live next to the named fonts; the ':' keeps them apart from config names.
+func RegisterFontWeight(weight int, fontPath string) error {
+	return RegisterFont(fontWeightName(weight), fontPath)
+}
+
+// FontForWeight returns the font name (for GetFont) of the default font's variant closest to
+// weight. Ties keep the default font, then the lighter variant. "" means the default font.
+func FontForWeight(weight int) string {
+	fontsMu.RLock()
+	defer fontsMu.RUnlock()
+
+	best, bestDiff := "", abs(weight-defaultFontWeight)
+	for w := 100; w <= 900; w += 100 {
+		name := fontWeightName(w)
+		if _, ok := fonts[name]; ok && abs(weight-w) < bestDiff {
+			best, bestDiff = name, abs(weight-w)
+		}
+	}
+	return best
+}
+
+func fontWeightName(weight int) string {
+	return fmt.Sprintf("weight:%d", weight)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// FontRegistered reports whether name was registered with RegisterFont (weight variants excluded).
+func FontRegistered(name string) bool {
+	if strings.Contains(name, ":") {
+		return false
 	}
+	fontsMu.RLock()
+	defer fontsMu.RUnlock()
+	_, ok := fonts[name]
+	return ok
+}
+
+func parseFontFile(fontPath string) (*opentype.Font, error) {
 	fontBytes, err := os.ReadFile(fontPath)
 	if err != nil {
-		return fmt.Errorf("failed to read font file: %w", err)
+		return nil, fmt.Errorf("failed to read font file: %w", err)
 	}
-	parsedFont, err = opentype.Parse(fontBytes)
+	f, err := opentype.Parse(fontBytes)
 	if err != nil {
-		return fmt.Errorf("failed to parse font file: %w", err)
+		return nil, fmt.Errorf("failed to parse font file: %w", err)
 	}
-	return nil
+	return f, nil
 }
 
-func GetFont(fontPath string, size int) font.Face {
-	if parsedFont == nil {
+// FontLoaded reports whether InitFonts succeeded.
+func FontLoaded() bool {
+	fontsMu.RLock()
+	defer fontsMu.RUnlock()
+	return parsedFont != nil
+}
+
+// CanDraw reports whether the font (see GetFont) has a glyph for every rune of text (spaces
+// aside), so drawing it never shows a missing-glyph box. Without a loaded font there is
+// nothing to check against and it reports true.
+func CanDraw(text string, fontName string) bool {
+	f := lookupFont(fontName)
+	if f == nil {
+		return true
+	}
+	var buf sfnt.Buffer
+	for _, r := range text {
+		if r == ' ' {
+			continue
+		}
+		if gi, err := f.GlyphIndex(&buf, r); err != nil || gi == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// faceKey identifies interchangeable faces: same parsed font, same pixel size.
+type faceKey struct {
+	font *opentype.Font
+	size int
+}
+
+// facePools recycles faces per (font, size). A face keeps internal glyph buffers and is not
+// safe for concurrent use, so faces are pooled instead of shared. faceKey -> *sync.Pool
+var facePools sync.Map
+
+// GetFont returns a face of the named font ("" or an unknown name = default font).
+// Faces are reused: hand the face back with PutFont once done drawing.
+func GetFont(fontName string, size int) font.Face {
+	f := lookupFont(fontName)
+	if f == nil {
 		logger.LogWarn("⚠️ Font not initialized! Call InitFonts first.")
 		return nil
 	}
 
-	face, err := opentype.NewFace(parsedFont, &opentype.FaceOptions{
+	pool := facePool(f, size)
+	if face, ok := pool.Get().(font.Face); ok {
+		return face
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
 		Size:    float64(size),
 		DPI:     72,
 		Hinting: font.HintingFull,
@@ -52,3 +172,33 @@ func GetFont(fontPath string, size int) font.Face {
 
 	return face
 }
+
+// PutFont returns a face obtained from GetFont with the same name and size for reuse.
+// The caller must not use the face afterwards.
+func PutFont(fontName string, size int, face font.Face) {
+	if face == nil {
+		return
+	}
+	if f := lookupFont(fontName); f != nil {
+		facePool(f, size).Put(face)
+	}
+}
+
+func facePool(f *opentype.Font, size int) *sync.Pool {
+	key := faceKey{font: f, size: size}
+	if pool, ok := facePools.Load(key); ok {
+		return pool.(*sync.Pool)
+	}
+	pool, _ := facePools.LoadOrStore(key, &sync.Pool{})
+	return pool.(*sync.Pool)
+}
+
+// lookupFont resolves a registered name, falling back to the default font.
+func lookupFont(fontName string) *opentype.Font {
+	fontsMu.RLock()
+	defer fontsMu.RUnlock()
+	if f, ok := fonts[fontName]; ok {
+		return f
+	}
+	return parsedFont
+}
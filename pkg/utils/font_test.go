@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+const benchFontSize = 100 // CalculateFontSize for two initials on a 256px avatar
+
+// BenchmarkFaceNew is the per-render cost GetFont avoids: a fresh face for every avatar.
+func BenchmarkFaceNew(b *testing.B) {
+	loadTestFont(b)
+	f := lookupFont("")
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: benchFontSize, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			b.Fatal(err)
+		}
+		font.MeasureString(face, "AB")
+	}
+}
+
+// BenchmarkFacePooled takes faces from the pool and hands them back, as DrawText does.
+func BenchmarkFacePooled(b *testing.B) {
+	loadTestFont(b)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		face := GetFont("", benchFontSize)
+		font.MeasureString(face, "AB")
+		PutFont("", benchFontSize, face)
+	}
+}
+
+// BenchmarkFacePooledParallel checks the pool under concurrent renders (run with -race too).
+func BenchmarkFacePooledParallel(b *testing.B) {
+	loadTestFont(b)
+	b.ReportAllocs()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			face := GetFont("", benchFontSize)
+			font.MeasureString(face, "AB")
+			PutFont("", benchFontSize, face)
+		}
+	})
+}
+
+// BenchmarkDrawText is a whole initials render onto a 256px canvas.
+func BenchmarkDrawText(b *testing.B) {
+	loadTestFont(b)
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		DrawText(img, "AB", color.White, 256, "")
+	}
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/png"
+)
+
+// EncodeICO packs PNG images into one multi-resolution .ico file.
+// PNG-compressed entries are supported by every browser and Windows Vista+.
+func EncodeICO(pngs [][]byte) ([]byte, error) {
+	const headerSize, entrySize = 6, 16
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, [3]uint16{0, 1, uint16(len(pngs))}) // Reserved, type (1 = icon), count
+
+	offset := headerSize + entrySize*len(pngs)
+	for _, data := range pngs {
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("ico entry is not a PNG: %w", err)
+		}
+		if cfg.Width > 256 || cfg.Height > 256 {
+			return nil, fmt.Errorf("ico entry %dx%d exceeds 256x256", cfg.Width, cfg.Height)
+		}
+
+		buf.WriteByte(byte(cfg.Width)) // 256 wraps to 0, which ICO reads as 256
+		buf.WriteByte(byte(cfg.Height))
+		buf.Write([]byte{0, 0})                                   // Palette size, reserved
+		binary.Write(&buf, binary.LittleEndian, [2]uint16{1, 32}) // Color planes, bits per pixel
+		binary.Write(&buf, binary.LittleEndian, [2]uint32{uint32(len(data)), uint32(offset)})
+		offset += len(data)
+	}
+
+	for _, data := range pngs {
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
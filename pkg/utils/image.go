@@ -5,9 +5,11 @@ import (
 	"octa/pkg/logger"
 
 	"fmt"
+	"html"
 
 	"image"
 	"image/color"
+	"math"
 
 	"strings"
 	"unicode"
@@ -182,21 +184,80 @@ func DetermineTextColorAdvanced(
 	}
 
 
-	var base color.RGBA
-
+	// Text sits over every part of a gradient, so both stops and the midpoint must stay readable.
+	stops := []color.RGBA{c1}
 	if aType == "gradient" {
-		base = DominantFromGradient(c1, c2)
-	} else {
-		base = c1
+		stops = append(stops, c2, DominantFromGradient(c1, c2))
 	}
 
-	if Luminance(base) > 0.6 {
+	// Pick whichever of black/white has the better worst-case WCAG contrast.
+	// Against a solid color one of them always reaches AA (the worst case is ~4.58:1).
+	if worstContrast(color.RGBA{A: 255}, stops) >= worstContrast(color.RGBA{255, 255, 255, 255}, stops) {
 		return color.Black
 	}
 
 	return color.White
 }
 
+// WCAGMinContrast is the AA threshold for normal text.
+const WCAGMinContrast = 4.5
+
+// RelativeLuminance: WCAG 2.x relative luminance (linearized sRGB), 0..1.
+func RelativeLuminance(c color.RGBA) float64 {
+	lin := func(v uint8) float64 {
+		f := float64(v) / 255.0
+		if f <= 0.03928 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	return 0.2126*lin(c.R) + 0.7152*lin(c.G) + 0.0722*lin(c.B)
+}
+
+// ContrastRatio: WCAG contrast ratio between two colors, 1..21.
+func ContrastRatio(a, b color.RGBA) float64 {
+	la, lb := RelativeLuminance(a), RelativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// EnsureContrast: Nudges bg lightness away from text until the pair reaches WCAG AA.
+// Keeps hue and saturation; returns bg unchanged if it is already readable.
+func EnsureContrast(bg color.RGBA, text color.Color) color.RGBA {
+	txt := color.RGBAModel.Convert(text).(color.RGBA)
+	// Contrast over a translucent background depends on what is behind it; leave it to the user.
+	if bg.A < 255 || ContrastRatio(bg, txt) >= WCAGMinContrast {
+		return bg
+	}
+
+	step := 0.02
+	if RelativeLuminance(txt) > RelativeLuminance(bg) {
+		step = -step // Light text: darken the background
+	}
+
+	h, s, l := rgbToHsl(bg.R, bg.G, bg.B)
+	out := bg
+	for ContrastRatio(out, txt) < WCAGMinContrast {
+		l += step
+		if l < 0 || l > 1 {
+			break
+		}
+		r, g, b := hslToRgb(h, s, l)
+		out = color.RGBA{r, g, b, bg.A}
+	}
+	return out
+}
+
+func worstContrast(text color.RGBA, stops []color.RGBA) float64 {
+	worst := math.Inf(1)
+	for _, s := range stops {
+		worst = math.Min(worst, ContrastRatio(s, text))
+	}
+	return worst
+}
+
 func DetermineTextColor(bg color.RGBA, input string) color.Color {
 	switch strings.ToLower(input) {
 	case "white":
@@ -211,7 +272,33 @@ func DetermineTextColor(bg color.RGBA, input string) color.Color {
 	}
 }
 
-func CalculateFontSize(size int, text string) int {
+// Initials sizing, shared by the PNG, SVG and PDF renders.
+const (
+	textMaxHeightRatio = 0.5  // Largest font size, as a fraction of the avatar size
+	textMaxWidthRatio  = 0.7  // Widest the measured text may get, as a fraction of the avatar size
+	textFitRatio       = 0.85 // Hard limit for the drawn (hinted) PNG text width
+	minTextSize        = 6    // Smallest font size the PNG fit loop shrinks to
+)
+
+// CalculateFontSize starts at textMaxHeightRatio of the avatar and shrinks the font until the
+// text, measured with the named font (see GetFont), is at most textMaxWidthRatio wide. Unhinted widths scale
+// linearly with the font size, so one measurement is enough.
+// Without a loaded font it falls back to fixed per-length ratios.
+func CalculateFontSize(size int, text string, fontName string) int {
+	fontSize := int(float64(size) * textMaxHeightRatio)
+
+	width, ok := measureText(lookupFont(fontName), text, fontSize)
+	if !ok {
+		return fallbackFontSize(size, text)
+	}
+	if maxWidth := float64(size) * textMaxWidthRatio; width > maxWidth {
+		fontSize = int(float64(fontSize) * maxWidth / width)
+	}
+	return max(fontSize, 1)
+}
+
+// fallbackFontSize guesses from the character count when the text can't be measured.
+func fallbackFontSize(size int, text string) int {
 	base := float64(size) * 0.6 
 
 	switch len([]rune(text)) {
@@ -224,6 +311,29 @@ func CalculateFontSize(size int, text string) int {
 	}
 }
 
+// svgShape is the avatar background: a <circle> when the radius spans the whole box
+// (shape=circle, rounded=50), otherwise a rounded <rect>. A ring is a stroke, which SVG centers
+// on the outline, so the shape is inset by half its width to keep the ring inside the bounds.
+func svgShape(size int, rounded int, fill string, ring float64, ringColor color.RGBA) string {
+	stroke := ""
+	inset := 0.0
+	if ring > 0 {
+		inset = ring / 2
+		stroke = fmt.Sprintf(` %s stroke-width="%g"`, SVGPaint("stroke", ringColor), ring)
+	}
+
+	if rounded > 0 && 2*rounded >= size-1 {
+		c := float64(size) / 2
+		return fmt.Sprintf(`<circle cx="%g" cy="%g" r="%g" %s%s />`, c, c, c-inset, fill, stroke)
+	}
+	if inset == 0 {
+		return fmt.Sprintf(`<rect width="%d" height="%d" rx="%d" ry="%d" %s />`, size, size, rounded, rounded, fill)
+	}
+	side := float64(size) - ring
+	rx := math.Max(float64(rounded)-inset, 0)
+	return fmt.Sprintf(`<rect x="%g" y="%g" width="%g" height="%g" rx="%g" ry="%g" %s%s />`, inset, inset, side, side, rx, rx, fill, stroke)
+}
+
 func GenerateSVG(
 	size int,
 	name string,
@@ -232,6 +342,11 @@ func GenerateSVG(
 	rounded int,
 	textColor color.Color,
 	aType string, // "gradient", "soft", "color"
+	embedFont bool, // Draw text as glyph outlines instead of relying on a client font
+	ring float64, // Border width in px inside the outline (0 = none)
+	ringColor color.RGBA,
+	fontName string, // Registered font for measuring and outlines ("" = default)
+	fontWeight int, // CSS font-weight of <text> (e.g. 600)
 ) string {
 
 	if aType == "" {
@@ -242,39 +357,61 @@ func GenerateSVG(
 		text = GetInitials(name)
 	}
 
-	fill := "white"
+	fill := `fill="white"`
 	if textColor != nil {
-		r, g, b, _ := textColor.RGBA()
-		fill = fmt.Sprintf("rgb(%d,%d,%d)", r>>8, g>>8, b>>8)
+		fill = SVGPaint("fill", textColor)
 	}
 
-	fontSize := CalculateFontSize(size, text)
+	fontSize := CalculateFontSize(size, text, fontName)
 
 	textSVG := ""
-	if text != "" {
+	if text != "" && embedFont {
+		// Outlines carry no text, so label them for screen readers and text extraction.
+		if d, ok := TextOutlinePath(text, fontSize, size, fontName); ok {
+			label := html.EscapeString(text)
+			textSVG = fmt.Sprintf(`
+	<g role="img" aria-label="%s">
+		<title>%s</title>
+		<path d="%s" %s />
+	</g>`, label, label, d, fill)
+		}
+	}
+	if text != "" && textSVG == "" {
+		// Same cap-height baseline as the PNG. Without a loaded font, let the client center the em box.
+		vertical := `y="50%" dominant-baseline="central"`
+		if baseline, ok := textBaseline(lookupFont(fontName), fontSize, size); ok {
+			vertical = fmt.Sprintf(`y="%.1f"`, baseline)
+		}
 		textSVG = fmt.Sprintf(`
 	<text
 		x="50%%"
-		y="50%%"
+		%s
 		text-anchor="middle"
-		dominant-baseline="central"
 		font-family="Inter, system-ui, -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif"
-		font-weight="600"
+		font-weight="%d"
 		font-size="%d"
-		fill="%s"
+		%s
 		letter-spacing="-0.03em"
-	>%s</text>`, fontSize, fill, text)
+	>%s</text>`, vertical, fontWeight, fontSize, fill, text)
+	}
+
+	transparent := bg1.A == 0 && bg2.A == 0
+	background := svgShape(size, rounded, SVGPaint("fill", bg1), ring, ringColor)
+	if transparent {
+		background = "" // bg=transparent: initials only (and the ring, if any)
+		if ring > 0 {
+			background = svgShape(size, rounded, `fill="none"`, ring, ringColor)
+		}
 	}
 
-	if aType == "soft" || aType == "color" {
+	if aType == "soft" || aType == "color" || transparent {
 		return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <svg width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">
-	<rect width="%d" height="%d" rx="%d" ry="%d" fill="rgb(%d,%d,%d)" />
+	%s
 	%s
 </svg>`,
 			size, size, size, size,
-			size, size, rounded, rounded,
-			bg1.R, bg1.G, bg1.B,
+			background,
 			textSVG,
 		)
 	}
@@ -284,47 +421,76 @@ func GenerateSVG(
 <svg width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">
 	<defs>
 		<linearGradient id="gradient" x1="1" y1="1" x2="0" y2="0">
-			<stop offset="0%%" stop-color="rgb(%d,%d,%d)" />
-			<stop offset="100%%" stop-color="rgb(%d,%d,%d)" />
+			<stop offset="0%%" %s />
+			<stop offset="100%%" %s />
 		</linearGradient>
 	</defs>
-	<rect width="%d" height="%d" rx="%d" ry="%d" fill="url(#gradient)" />
+	%s
 	%s
 </svg>`,
 		size, size, size, size,
-		bg1.R, bg1.G, bg1.B,
-		bg2.R, bg2.G, bg2.B,
-		size, size, rounded, rounded,
+		SVGPaint("stop-color", bg1),
+		SVGPaint("stop-color", bg2),
+		svgShape(size, rounded, `fill="url(#gradient)"`, ring, ringColor),
 		textSVG,
 	)
 }
 
-func DrawText(img *image.RGBA, text string, textColor color.Color, size int) {
+// SVGPaint renders a color as SVG attributes. Translucent colors get a matching
+// *-opacity attribute ("fill-opacity", "stop-opacity"), since rgba() is not valid SVG 1.1.
+func SVGPaint(attr string, c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	out := fmt.Sprintf(`%s="rgb(%d,%d,%d)"`, attr, n.R, n.G, n.B)
+	if n.A < 255 {
+		opacityAttr := strings.TrimSuffix(attr, "-color") + "-opacity"
+		out += fmt.Sprintf(` %s="%.3f"`, opacityAttr, float64(n.A)/255)
+	}
+	return out
+}
+
+// DrawText draws text centered in img with the named font ("" = default, see GetFont).
+func DrawText(img *image.RGBA, text string, textColor color.Color, size int, fontName string) {
 	col := textColor
 
-	fontSize := int(float64(size) / 2)
-	loadedFont := GetFont("fonts/Inter_24pt-Medium.ttf", fontSize)
+	fontSize := CalculateFontSize(size, text, fontName)
+	loadedFont := GetFont(fontName, fontSize)
 	if loadedFont == nil {
 		logger.LogError("Font failed to load. Unable to draw text.")
 		return
 	}
 
+	// Fit to bounds: hinting, glyphs missing from the font or long ?initials overrides can still
+	// spill past the estimate, so shrink until the drawn width fits.
+	maxWidth := int(float64(size) * textFitRatio)
+	minSize := max(minTextSize, size/8)
+	textWidth := font.MeasureString(loadedFont, text).Ceil()
+	for textWidth > maxWidth && fontSize > minSize {
+		next := max(minSize, min(fontSize-1, fontSize*maxWidth/textWidth))
+		PutFont(fontName, fontSize, loadedFont)
+		fontSize = next
+		if loadedFont = GetFont(fontName, fontSize); loadedFont == nil {
+			return
+		}
+		textWidth = font.MeasureString(loadedFont, text).Ceil()
+	}
+	defer PutFont(fontName, fontSize, loadedFont)
+
 	d := &font.Drawer{
 		Dst:  img,
 		Src:  image.NewUniform(col),
 		Face: loadedFont,
 	}
 
-	textWidth := d.MeasureString(text).Round()
-
-	metrics := loadedFont.Metrics()
-	ascent := metrics.Ascent.Ceil()
-	descent := metrics.Descent.Ceil()
-	textHeight := ascent + descent
-
 	// >_ Postion(Center)
+	// Vertically, the cap height is centered (optical centering): initials are almost always
+	// capitals, so centering ascent+descent would leave the unused descender space below them.
+	metrics := loadedFont.Metrics()
+	capHeight := metrics.CapHeight
+	if capHeight <= 0 {
+		capHeight = metrics.Ascent - metrics.Descent // Font without an OS/2 cap height: old ascent-based centering
+	}
 	x := (size - textWidth) / 2
-	y := (size-textHeight)/2 + ascent
+	y := ((fixed.I(size) + capHeight) / 2).Round() // Whole-pixel baseline keeps hinted glyphs crisp
 
 	d.Dot = fixed.P(x, y)
 	d.DrawString(text)
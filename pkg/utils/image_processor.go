@@ -4,24 +4,57 @@ import (
 	"bytes"
 	"github.com/disintegration/imaging"
 	"image"
+	"image/color"
 	"image/jpeg"
+	"image/png"
+
+	"github.com/gen2brain/avif"
+	"github.com/gen2brain/webp"
 )
 
+// MaxPaddingPercent keeps at least a fifth of the canvas for the image itself.
+const MaxPaddingPercent = 40
+
 type ProcessOptions struct {
-	Mode    string // "square", "fit", "original", "scale"
+	Mode    string // "square" (alias "cover"), "contain", "fit", "original", "scale"
 	Size    int    // Pixel-based size (256, 512, etc.)
 	Scale   int    // Percentage-based size (1-100)
 	Quality int
+	Format  string // Output encoding: "jpeg" (default), "png" or "webp"
+
+	// Background fills the letterbox of "contain" and the padding border. nil = transparent (white for jpeg, which has no alpha).
+	Background color.Color
+	Padding    int // Border on each side as a percentage of the shorter side (0-MaxPaddingPercent)
+
+	Watermark bool // Composite the startup-loaded watermark (ignored for "original")
 }
 
-func ProcessImage(img image.Image, opts ProcessOptions) (*bytes.Buffer, int, int, error) {
+// ProcessedImage is the encoded output of ProcessImage.
+type ProcessedImage struct {
+	Data          []byte
+	Width, Height int
+	Format        string // Encoding actually used: "jpeg", "png" or "webp"
+}
+
+func ProcessImage(img image.Image, opts ProcessOptions) (ProcessedImage, error) {
 	var finalImg image.Image
 
 	switch opts.Mode {
-	case "square":
+	case "square", "cover":
 		// Make a square and cut it in half
 		finalImg = imaging.Fill(img, opts.Size, opts.Size, imaging.Center, imaging.Lanczos)
 
+	case "contain":
+		// Whole image scaled into the square, letterbox filled with the background
+		var fitted image.Image
+		if img.Bounds().Dx() >= img.Bounds().Dy() {
+			fitted = imaging.Resize(img, opts.Size, 0, imaging.Lanczos)
+		} else {
+			fitted = imaging.Resize(img, 0, opts.Size, imaging.Lanczos)
+		}
+		canvas := imaging.New(opts.Size, opts.Size, processBackground(opts))
+		finalImg = imaging.OverlayCenter(canvas, fitted, 1.0)
+
 	case "fit":
 		// Fit to pixel limit (e.g., maximum 1024px)
 		if img.Bounds().Dx() > opts.Size || img.Bounds().Dy() > opts.Size {
@@ -55,8 +88,79 @@ func ProcessImage(img image.Image, opts ProcessOptions) (*bytes.Buffer, int, int
 		finalImg = imaging.Fill(img, 256, 256, imaging.Center, imaging.Lanczos)
 	}
 
+	if opts.Padding > 0 && opts.Mode != "original" {
+		finalImg = padImage(finalImg, opts)
+	}
+
+	if opts.Watermark && opts.Mode != "original" {
+		finalImg = ApplyWatermark(finalImg)
+	}
+
+	out := ProcessedImage{Width: finalImg.Bounds().Dx(), Height: finalImg.Bounds().Dy(), Format: opts.Format}
+	buf := new(bytes.Buffer)
+	var err error
+	switch opts.Format {
+	case "webp":
+		var data []byte
+		if data, err = EncodeWebP(finalImg, opts.Quality); err == nil {
+			buf.Write(data)
+		}
+	case "png":
+		err = png.Encode(buf, finalImg)
+	default:
+		out.Format = "jpeg"
+		err = jpeg.Encode(buf, finalImg, &jpeg.Options{Quality: opts.Quality})
+	}
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+
+	out.Data = buf.Bytes()
+	return out, nil
+}
+
+// padImage shrinks img inside a canvas of the same dimensions, leaving an even border
+// filled with the background color (e.g. breathing room around a logo for app icons).
+func padImage(img image.Image, opts ProcessOptions) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	pad := min(w, h) * min(opts.Padding, MaxPaddingPercent) / 100
+	if pad <= 0 {
+		return img
+	}
+
+	inner := imaging.Fit(img, w-2*pad, h-2*pad, imaging.Lanczos)
+	canvas := imaging.New(w, h, processBackground(opts))
+	return imaging.OverlayCenter(canvas, inner, 1.0)
+}
+
+// processBackground resolves the fill color for letterboxed and padded output.
+func processBackground(opts ProcessOptions) color.Color {
+	if opts.Background != nil {
+		return opts.Background
+	}
+	if opts.Format == "png" || opts.Format == "webp" {
+		return color.Transparent
+	}
+	return color.White
+}
+
+// ProcessFormats lists the output encodings accepted by ProcessOptions.Format.
+var ProcessFormats = map[string]bool{"jpeg": true, "png": true, "webp": true}
+
+// EncodeAVIF encodes img as lossy AVIF (pure Go via WASM). Much slower than WebP; callers gate it.
+func EncodeAVIF(img image.Image, quality int) ([]byte, error) {
 	buf := new(bytes.Buffer)
-	err := jpeg.Encode(buf, finalImg, &jpeg.Options{Quality: opts.Quality})
+	if err := avif.Encode(buf, img, avif.Options{Quality: quality, QualityAlpha: quality, Speed: avif.DefaultSpeed}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
 
-	return buf, finalImg.Bounds().Dx(), finalImg.Bounds().Dy(), err
+// EncodeWebP encodes img as lossy WebP (pure Go via WASM, no cgo required).
+func EncodeWebP(img image.Image, quality int) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := webp.Encode(buf, img, webp.Options{Quality: quality, Method: webp.DefaultMethod}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
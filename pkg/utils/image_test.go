@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testFontPath = "../../fonts/Inter_28pt-SemiBold.ttf"
+
+func loadTestFont(t testing.TB) {
+	t.Helper()
+	if err := InitFonts(testFontPath); err != nil {
+		t.Fatalf("InitFonts: %v", err)
+	}
+}
+
+type bbox struct{ minX, minY, maxX, maxY float64 }
+
+func (b bbox) center() (float64, float64) { return (b.minX + b.maxX) / 2, (b.minY + b.maxY) / 2 }
+
+func newBBox() bbox {
+	return bbox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+func (b *bbox) add(x, y float64) {
+	b.minX, b.maxX = math.Min(b.minX, x), math.Max(b.maxX, x)
+	b.minY, b.maxY = math.Min(b.minY, y), math.Max(b.maxY, y)
+}
+
+var (
+	svgPathData = regexp.MustCompile(`<path d="([^"]+)"`)
+	svgTextY    = regexp.MustCompile(`<text[^>]*\sy="([0-9.]+)"`)
+)
+
+// The same initials must sit at the same place whether the avatar is rendered as PNG or SVG.
+// "HE" has only straight edges, so the outline's points are exactly its bounding box.
+func TestTextCenterParityPNGvsSVG(t *testing.T) {
+	loadTestFont(t)
+
+	const text = "HE"
+	for _, size := range []int{64, 128, 256, 512} {
+		// PNG: white text on black, bounding box of the covered pixels (edges count from half coverage)
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+		DrawText(img, text, color.White, size, "")
+
+		png := newBBox()
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				if img.RGBAAt(x, y).R >= 0x80 {
+					png.add(float64(x), float64(y))
+					png.add(float64(x+1), float64(y+1))
+				}
+			}
+		}
+		if math.IsInf(png.minX, 1) {
+			t.Fatalf("size %d: PNG has no text pixels", size)
+		}
+
+		// SVG: the embedded glyph outline from the generated document
+		doc := GenerateSVG(size, text, color.RGBA{A: 255}, color.RGBA{A: 255}, text, 0, color.White,
+			"color", true, 0, color.RGBA{}, "", 600)
+		m := svgPathData.FindStringSubmatch(doc)
+		if m == nil {
+			t.Fatalf("size %d: SVG has no outline path:\n%s", size, doc)
+		}
+		svg := newBBox()
+		nums := strings.FieldsFunc(m[1], func(r rune) bool { return strings.ContainsRune("MLQCZ ", r) })
+		if len(nums)%2 != 0 {
+			t.Fatalf("size %d: odd number of path coordinates", size)
+		}
+		for i := 0; i < len(nums); i += 2 {
+			x, errX := strconv.ParseFloat(nums[i], 64)
+			y, errY := strconv.ParseFloat(nums[i+1], 64)
+			if errX != nil || errY != nil {
+				t.Fatalf("size %d: bad path coordinates %q %q", size, nums[i], nums[i+1])
+			}
+			svg.add(x, y)
+		}
+
+		// Hinting and whole-pixel pen positions in the PNG allow about a pixel of difference.
+		pngX, pngY := png.center()
+		svgX, svgY := svg.center()
+		if math.Abs(pngX-svgX) > 1.5 || math.Abs(pngY-svgY) > 1.5 {
+			t.Errorf("size %d: text center PNG (%.1f, %.1f), SVG (%.1f, %.1f)", size, pngX, pngY, svgX, svgY)
+		}
+
+		// SVG <text> (font not embedded): the client lays it out, so check its baseline instead.
+		// "HE" has no descenders, so the baseline is the bottom of the PNG's box.
+		doc = GenerateSVG(size, text, color.RGBA{A: 255}, color.RGBA{A: 255}, text, 0, color.White,
+			"color", false, 0, color.RGBA{}, "", 600)
+		if strings.Contains(doc, "dominant-baseline") {
+			t.Errorf("size %d: <text> is centered by the client (dominant-baseline), not at the PNG baseline", size)
+			continue
+		}
+		m = svgTextY.FindStringSubmatch(doc)
+		if m == nil {
+			t.Fatalf("size %d: <text> has no y baseline:\n%s", size, doc)
+		}
+		baseline, _ := strconv.ParseFloat(m[1], 64)
+		if math.Abs(baseline-png.maxY) > 1.5 {
+			t.Errorf("size %d: <text> baseline %.1f, PNG text bottom %.1f", size, baseline, png.maxY)
+		}
+	}
+}
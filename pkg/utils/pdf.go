@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// bezierCircle is the control point distance for approximating a quarter circle with a cubic curve.
+const bezierCircle = 0.5523
+
+// GeneratePDF draws the avatar as a single-page vector PDF whose page is size x size points.
+// It mirrors GenerateSVG: same shape, gradient direction, font size and glyph outlines
+// for the initials (PDF has no font fallback, so text is always drawn as paths).
+func GeneratePDF(
+	size int,
+	bg1, bg2 color.RGBA,
+	text string,
+	rounded int,
+	textColor color.RGBA,
+	aType string, // "gradient", "soft", "color"
+	ring float64, // Border width in points inside the outline (0 = none)
+	ringColor color.RGBA,
+	fontName string, // Registered font for the initials' outlines ("" = default)
+) []byte {
+	s := float64(size)
+	var content bytes.Buffer
+	extGStates := map[string]float64{} // Name -> fill opacity
+
+	// Background (none for bg=transparent: initials only)
+	if bg1.A > 0 || bg2.A > 0 {
+		content.WriteString("q\n")
+		writePDFRoundedRect(&content, 0, s, float64(rounded))
+		if aType == "gradient" && bg1 != bg2 {
+			// Clip to the shape, then paint the axial shading (bottom-right bg1 -> top-left bg2, like the SVG).
+			content.WriteString("W n\n/Sh1 sh\n")
+		} else {
+			writePDFFill(&content, extGStates, "GSbg", bg1)
+			content.WriteString("f\n")
+		}
+		content.WriteString("Q\n")
+	}
+
+	// Ring: the band between the outline and the same shape inset by ring (even-odd fill)
+	if ring > 0 {
+		content.WriteString("q\n")
+		writePDFFill(&content, extGStates, "GSring", ringColor)
+		writePDFRoundedRect(&content, 0, s, float64(rounded))
+		writePDFRoundedRect(&content, ring, s, math.Max(float64(rounded)-ring, 0))
+		content.WriteString("f*\nQ\n")
+	}
+
+	// Initials
+	if segments, ok := textOutline(lookupFont(fontName), text, CalculateFontSize(size, text, fontName), size); ok {
+		content.WriteString("q\n")
+		writePDFFill(&content, extGStates, "GSfg", textColor)
+		writePDFOutline(&content, segments, s)
+		content.WriteString("f\nQ\n")
+	}
+
+	// Resources
+	var res strings.Builder
+	if len(extGStates) > 0 {
+		res.WriteString("/ExtGState <<")
+		for _, name := range []string{"GSbg", "GSring", "GSfg"} { // Fixed order: output must be byte-stable
+			if alpha, ok := extGStates[name]; ok {
+				fmt.Fprintf(&res, " /%s << /ca %s >>", name, pdfNum(alpha))
+			}
+		}
+		res.WriteString(" >> ")
+	}
+	if aType == "gradient" && bg1 != bg2 {
+		c1, c2 := pdfRGB(bg1), pdfRGB(bg2)
+		fmt.Fprintf(&res, "/Shading << /Sh1 << /ShadingType 2 /ColorSpace /DeviceRGB /Coords [%s 0 0 %s] "+
+			"/Function << /FunctionType 2 /Domain [0 1] /C0 [%s] /C1 [%s] /N 1 >> /Extend [true true] >> >> ",
+			pdfNum(s), pdfNum(s), c1, c2)
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << %s>> /Contents 4 0 R >>", size, size, res.String()),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+	}
+
+	return writePDFDocument(objects)
+}
+
+// writePDFDocument serializes numbered objects (1-based, in order) with a valid xref table.
+func writePDFDocument(objects []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// writePDFRoundedRect appends the avatar shape, inset by o on every side, as a path.
+// PDF's origin is bottom-left.
+func writePDFRoundedRect(buf *bytes.Buffer, o, s, r float64) {
+	lo, hi := o, s-o
+	if r <= 0 {
+		fmt.Fprintf(buf, "%s %s %s %s re\n", pdfNum(lo), pdfNum(lo), pdfNum(hi-lo), pdfNum(hi-lo))
+		return
+	}
+	k := r * bezierCircle
+	fmt.Fprintf(buf, "%s %s m\n", pdfNum(lo+r), pdfNum(lo))
+	fmt.Fprintf(buf, "%s %s l\n", pdfNum(hi-r), pdfNum(lo))
+	fmt.Fprintf(buf, "%s %s %s %s %s %s c\n", pdfNum(hi-r+k), pdfNum(lo), pdfNum(hi), pdfNum(lo+r-k), pdfNum(hi), pdfNum(lo+r))
+	fmt.Fprintf(buf, "%s %s l\n", pdfNum(hi), pdfNum(hi-r))
+	fmt.Fprintf(buf, "%s %s %s %s %s %s c\n", pdfNum(hi), pdfNum(hi-r+k), pdfNum(hi-r+k), pdfNum(hi), pdfNum(hi-r), pdfNum(hi))
+	fmt.Fprintf(buf, "%s %s l\n", pdfNum(lo+r), pdfNum(hi))
+	fmt.Fprintf(buf, "%s %s %s %s %s %s c\n", pdfNum(lo+r-k), pdfNum(hi), pdfNum(lo), pdfNum(hi-r+k), pdfNum(lo), pdfNum(hi-r))
+	fmt.Fprintf(buf, "%s %s l\n", pdfNum(lo), pdfNum(lo+r))
+	fmt.Fprintf(buf, "%s %s %s %s %s %s c\nh\n", pdfNum(lo), pdfNum(lo+r-k), pdfNum(lo+r-k), pdfNum(lo), pdfNum(lo+r), pdfNum(lo))
+}
+
+// writePDFOutline appends glyph outlines, flipping y and raising quadratic curves to cubic ones.
+func writePDFOutline(buf *bytes.Buffer, segments []outlineSegment, s float64) {
+	pt := func(p [2]float64) string { return pdfNum(p[0]) + " " + pdfNum(s-p[1]) }
+
+	var cur [2]float64
+	for i, seg := range segments {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			if i > 0 {
+				buf.WriteString("h\n")
+			}
+			fmt.Fprintf(buf, "%s m\n", pt(seg.Pts[0]))
+		case sfnt.SegmentOpLineTo:
+			fmt.Fprintf(buf, "%s l\n", pt(seg.Pts[0]))
+		case sfnt.SegmentOpQuadTo:
+			ctrl, end := seg.Pts[0], seg.Pts[1]
+			c1 := [2]float64{cur[0] + 2.0/3*(ctrl[0]-cur[0]), cur[1] + 2.0/3*(ctrl[1]-cur[1])}
+			c2 := [2]float64{end[0] + 2.0/3*(ctrl[0]-end[0]), end[1] + 2.0/3*(ctrl[1]-end[1])}
+			fmt.Fprintf(buf, "%s %s %s c\n", pt(c1), pt(c2), pt(end))
+		case sfnt.SegmentOpCubeTo:
+			fmt.Fprintf(buf, "%s %s %s c\n", pt(seg.Pts[0]), pt(seg.Pts[1]), pt(seg.Pts[2]))
+		}
+		cur = seg.Pts[len(seg.Pts)-1]
+	}
+	buf.WriteString("h\n")
+}
+
+// writePDFFill sets the fill color, registering an ExtGState for translucent colors.
+func writePDFFill(buf *bytes.Buffer, extGStates map[string]float64, name string, c color.RGBA) {
+	if c.A < 255 {
+		extGStates[name] = float64(c.A) / 255
+		fmt.Fprintf(buf, "/%s gs\n", name)
+	}
+	fmt.Fprintf(buf, "%s rg\n", pdfRGB(c))
+}
+
+// pdfRGB formats a (premultiplied) color as "r g b" components in 0-1.
+func pdfRGB(c color.RGBA) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return pdfNum(float64(n.R)/255) + " " + pdfNum(float64(n.G)/255) + " " + pdfNum(float64(n.B)/255)
+}
+
+// pdfNum formats a number compactly with at most 3 decimals (PDF accepts no exponent notation).
+func pdfNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+
+	"octa/internal/config"
 )
 
 const (
@@ -27,6 +29,7 @@ const (
 	// Server Error Codes
 	ErrServerInternal = "server/internal_error"
 	ErrServerTimeout  = "server/timeout"
+	ErrServerBusy     = "server/busy"
 
 	// Validation & Resource Error Codes
 	ErrValidationInvalidFormat = "validation/invalid_format"
@@ -37,6 +40,7 @@ const (
 	ErrImageGenerationFailed = "image/generation_failed"
 	ErrImageProcessingFailed = "image/processing_failed"
 	ErrUpstreamFailed        = "upstream/service_failed" // Github vs.
+	ErrContentRejected       = "content/rejected"        // Upload moderation
 
 	ErrBackupConcurrencyLimit = "backup/concurrency_limit"
 	ErrBackupForbiddenOrigin  = "backup/forbidden_origin"
@@ -52,9 +56,16 @@ type APIError struct {
 	Status  int    `json:"status"`  // HTTP Status Code
 }
 
-// WriteError sends a JSON formatted error response
+// WriteError sends a JSON formatted error response.
+// In production (server.env), 5xx messages are replaced with a generic text so internal
+// details don't leak; the original message is still printed server-side. 4xx messages stay as-is.
 func WriteError(w http.ResponseWriter, status int, code string, message string) {
 	fmt.Println(code, ": ", message)
+
+	if status >= 500 && isProduction() {
+		message = genericServerMessage(status)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(APIError{
@@ -69,3 +80,18 @@ func WriteJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
+
+func isProduction() bool {
+	return config.AppConfig != nil && config.AppConfig.Server.Env == "production"
+}
+
+func genericServerMessage(status int) string {
+	switch status {
+	case http.StatusServiceUnavailable:
+		return "Service temporarily unavailable. Please retry."
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return "Upstream service error."
+	default:
+		return "Internal server error."
+	}
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// svgLetterSpacing mirrors the letter-spacing="-0.03em" of the <text> variant.
+const svgLetterSpacing = -0.03
+
+// outlineSegment is one glyph outline command in avatar pixel space (y-down).
+type outlineSegment struct {
+	Op  sfnt.SegmentOp
+	Pts [][2]float64 // 1 point for move/line, 2 for quad, 3 for cubic
+}
+
+// TextOutlinePath converts text into an SVG path "d" attribute using the named font (see GetFont),
+// centered in a size x size box the same way DrawText centers PNG initials.
+// The result renders identically without the font installed (downloads, email clients).
+// ok=false means the font is not loaded or lacks a glyph; callers fall back to <text>.
+func TextOutlinePath(text string, fontSize int, size int, fontName string) (d string, ok bool) {
+	segments, ok := textOutline(lookupFont(fontName), text, fontSize, size)
+	if !ok {
+		return "", false
+	}
+
+	var sb strings.Builder
+	for _, seg := range segments {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			if sb.Len() > 0 {
+				sb.WriteByte('Z')
+			}
+			sb.WriteByte('M')
+		case sfnt.SegmentOpLineTo:
+			sb.WriteByte('L')
+		case sfnt.SegmentOpQuadTo:
+			sb.WriteByte('Q')
+		case sfnt.SegmentOpCubeTo:
+			sb.WriteByte('C')
+		}
+		for _, p := range seg.Pts {
+			writeSVGPoint(&sb, p[0], p[1])
+		}
+	}
+	sb.WriteByte('Z')
+
+	return sb.String(), true
+}
+
+// textOutline lays out text with f and returns the glyph outlines,
+// already translated so the text is centered in a size x size box (cap height vertically).
+func textOutline(f *opentype.Font, text string, fontSize int, size int) ([]outlineSegment, bool) {
+	if f == nil || text == "" {
+		return nil, false
+	}
+
+	var buf sfnt.Buffer
+	ppem := fixed.I(fontSize)
+	spacing := fixed.Int26_6(svgLetterSpacing * float64(fontSize) * 64)
+
+	glyphs, pens, width, ok := layoutText(&buf, f, text, ppem, spacing)
+	if !ok {
+		return nil, false
+	}
+
+	baseline, ok := textBaseline(f, fontSize, size)
+	if !ok {
+		return nil, false
+	}
+	originX := (float64(size) - fixedToFloat(width)) / 2
+
+	// Outlines: sfnt segments are y-down like the avatar canvas, so only a translation is needed.
+	var out []outlineSegment
+	for i, idx := range glyphs {
+		segments, err := f.LoadGlyph(&buf, idx, ppem, nil)
+		if err != nil {
+			return nil, false
+		}
+		dx := originX + fixedToFloat(pens[i])
+		for _, seg := range segments {
+			pts := make([][2]float64, segmentArgs(seg.Op))
+			for j := range pts {
+				pts[j] = [2]float64{dx + fixedToFloat(seg.Args[j].X), baseline + fixedToFloat(seg.Args[j].Y)}
+			}
+			out = append(out, outlineSegment{Op: seg.Op, Pts: pts})
+		}
+	}
+	if len(out) == 0 {
+		return nil, false // Only whitespace: nothing to draw
+	}
+
+	return out, true
+}
+
+// layoutText places text on a line with f: glyph indices, pen positions
+// (relative to the first glyph) and the total advance width, kerning and spacing included.
+// ok=false means the font is not loaded or lacks a glyph.
+func layoutText(buf *sfnt.Buffer, f *opentype.Font, text string, ppem, spacing fixed.Int26_6) (glyphs []sfnt.GlyphIndex, pens []fixed.Int26_6, width fixed.Int26_6, ok bool) {
+	if f == nil {
+		return nil, nil, 0, false
+	}
+
+	runes := []rune(text)
+	glyphs = make([]sfnt.GlyphIndex, len(runes))
+	pens = make([]fixed.Int26_6, len(runes))
+	for i, r := range runes {
+		idx, err := f.GlyphIndex(buf, r)
+		if err != nil || idx == 0 {
+			return nil, nil, 0, false
+		}
+		if i > 0 {
+			if kern, err := f.Kern(buf, glyphs[i-1], idx, ppem, font.HintingNone); err == nil {
+				width += kern
+			}
+			width += spacing
+		}
+		adv, err := f.GlyphAdvance(buf, idx, ppem, font.HintingNone)
+		if err != nil {
+			return nil, nil, 0, false
+		}
+		glyphs[i], pens[i] = idx, width
+		width += adv
+	}
+	return glyphs, pens, width, true
+}
+
+// textBaseline is the y of the baseline that centers the cap height in a size px box,
+// matching DrawText so PNG, SVG and PDF initials sit at the same height.
+func textBaseline(f *opentype.Font, fontSize int, size int) (float64, bool) {
+	if f == nil {
+		return 0, false
+	}
+	var buf sfnt.Buffer
+	metrics, err := f.Metrics(&buf, fixed.I(fontSize), font.HintingNone)
+	if err != nil {
+		return 0, false
+	}
+	capHeight := metrics.CapHeight
+	if capHeight <= 0 {
+		capHeight = metrics.Ascent - metrics.Descent
+	}
+	return (float64(size) + fixedToFloat(capHeight)) / 2, true
+}
+
+// measureText returns the unhinted advance width of text at fontSize px in f.
+func measureText(f *opentype.Font, text string, fontSize int) (float64, bool) {
+	var buf sfnt.Buffer
+	_, _, width, ok := layoutText(&buf, f, text, fixed.I(fontSize), 0)
+	return fixedToFloat(width), ok
+}
+
+// segmentArgs is the number of points each segment op uses.
+func segmentArgs(op sfnt.SegmentOp) int {
+	switch op {
+	case sfnt.SegmentOpQuadTo:
+		return 2
+	case sfnt.SegmentOpCubeTo:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// writeSVGPoint appends "x y " with 0.1px precision, which is invisible at avatar sizes.
+func writeSVGPoint(sb *strings.Builder, x, y float64) {
+	sb.WriteString(strconv.FormatFloat(x, 'f', 1, 64))
+	sb.WriteByte(' ')
+	sb.WriteString(strconv.FormatFloat(y, 'f', 1, 64))
+	sb.WriteByte(' ')
+}
+
+func fixedToFloat(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"octa/internal/config"
+)
+
+const (
+	DefaultWatermarkOpacity  = 0.5
+	DefaultWatermarkScale    = 0.2 // Watermark width as a fraction of the image width
+	DefaultWatermarkPosition = "bottom-right"
+
+	watermarkTextSize = 48 // Source glyph size, rescaled when applied
+)
+
+// WatermarkPositions lists the accepted values for image.watermark.position.
+var WatermarkPositions = map[string]bool{
+	"top-left": true, "top-right": true, "bottom-left": true, "bottom-right": true, "center": true,
+}
+
+var (
+	watermarkMu  sync.RWMutex
+	watermarkImg image.Image
+	watermarkCfg config.WatermarkConfig
+)
+
+// InitWatermark loads the configured logo (or renders the text) once at startup.
+// Text watermarks require InitFonts to have been called.
+func InitWatermark(cfg config.WatermarkConfig) error {
+	var mark image.Image
+
+	switch {
+	case cfg.Image != "":
+		img, err := imaging.Open(cfg.Image)
+		if err != nil {
+			return fmt.Errorf("failed to load watermark image: %w", err)
+		}
+		mark = img
+	case strings.TrimSpace(cfg.Text) != "":
+		img, err := renderWatermarkText(strings.TrimSpace(cfg.Text))
+		if err != nil {
+			return err
+		}
+		mark = img
+	default:
+		return nil
+	}
+
+	if cfg.Opacity <= 0 || cfg.Opacity > 1 {
+		cfg.Opacity = DefaultWatermarkOpacity
+	}
+	if cfg.Scale <= 0 || cfg.Scale > 1 {
+		cfg.Scale = DefaultWatermarkScale
+	}
+	if !WatermarkPositions[cfg.Position] {
+		cfg.Position = DefaultWatermarkPosition
+	}
+
+	watermarkMu.Lock()
+	watermarkImg = mark
+	watermarkCfg = cfg
+	watermarkMu.Unlock()
+	return nil
+}
+
+// WatermarkLoaded reports whether a watermark is available to apply.
+func WatermarkLoaded() bool {
+	watermarkMu.RLock()
+	defer watermarkMu.RUnlock()
+	return watermarkImg != nil
+}
+
+// ApplyWatermark composites the cached watermark onto img. Returns img unchanged if none is loaded.
+func ApplyWatermark(img image.Image) image.Image {
+	watermarkMu.RLock()
+	mark, cfg := watermarkImg, watermarkCfg
+	watermarkMu.RUnlock()
+
+	if mark == nil {
+		return img
+	}
+
+	b := img.Bounds()
+	targetW := int(float64(b.Dx()) * cfg.Scale)
+	if targetW < 1 {
+		return img
+	}
+	mark = imaging.Resize(mark, targetW, 0, imaging.Lanczos)
+
+	margin := min(b.Dx(), b.Dy()) / 40
+	mw, mh := mark.Bounds().Dx(), mark.Bounds().Dy()
+
+	var pos image.Point
+	switch cfg.Position {
+	case "top-left":
+		pos = image.Pt(margin, margin)
+	case "top-right":
+		pos = image.Pt(b.Dx()-mw-margin, margin)
+	case "bottom-left":
+		pos = image.Pt(margin, b.Dy()-mh-margin)
+	case "center":
+		pos = image.Pt((b.Dx()-mw)/2, (b.Dy()-mh)/2)
+	default: // bottom-right
+		pos = image.Pt(b.Dx()-mw-margin, b.Dy()-mh-margin)
+	}
+
+	return imaging.Overlay(img, mark, pos, cfg.Opacity)
+}
+
+// renderWatermarkText draws white text with a dark drop shadow on a transparent canvas.
+func renderWatermarkText(text string) (image.Image, error) {
+	face := GetFont("", watermarkTextSize)
+	if face == nil {
+		return nil, fmt.Errorf("font not available for text watermark")
+	}
+	defer PutFont("", watermarkTextSize, face)
+
+	width := font.MeasureString(face, text).Ceil()
+	metrics := face.Metrics()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+	shadow := 2
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width+shadow, height+shadow))
+	baseline := metrics.Ascent.Ceil()
+
+	d := &font.Drawer{Dst: canvas, Face: face}
+
+	d.Src = image.NewUniform(color.RGBA{A: 160})
+	d.Dot = fixed.P(shadow, baseline+shadow)
+	d.DrawString(text)
+
+	d.Src = image.NewUniform(color.White)
+	d.Dot = fixed.P(0, baseline)
+	d.DrawString(text)
+
+	return canvas, nil
+}
@@ -205,7 +205,7 @@ func createDummyImage() []byte {
 
 func checkServerHealth(baseURL string) bool {
 	spinner, _ := pterm.DefaultSpinner.Start("Checking server...")
-	if resp, err := http.Get(baseURL + "/"); err == nil {
+	if resp, err := http.Get(baseURL + "/health"); err == nil {
 		resp.Body.Close()
 		spinner.Success("Server is UP! (" + baseURL + ")")
 		return true
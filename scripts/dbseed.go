@@ -3,23 +3,40 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
 	"io"
 	"math/rand"
 	"mime/multipart"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/fatih/color"
+	fcolor "github.com/fatih/color"
 	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
 )
 
-// COnfig
+// Defaults (overridable by env, then by flags)
 const (
-	ServerURL    = "http://localhost:9980/upload"
-	UploadSecret = "secret"
-	TotalImages  = 50
-	WorkerCount  = 5
+	DefaultServerURL    = "http://localhost:9980"
+	DefaultUploadSecret = "secret"
+	DefaultTotalImages  = 50
+	DefaultWorkerCount  = 5
+)
+
+// GLOBAL FLAGS
+var (
+	serverURL    string
+	uploadSecret string
+	totalImages  int
+	workerCount  int
+	offline      bool
+	seed         int64
 )
 
 var (
@@ -34,36 +51,72 @@ type Result struct {
 }
 
 func main() {
+	rootCmd := &cobra.Command{
+		Use:   "dbseed",
+		Short: "Seed an Octa server with sample uploads",
+		Run:   runSeed,
+	}
+
+	// Env: OCTA_SEED_URL, OCTA_SEED_SECRET, OCTA_SEED_TOTAL, OCTA_SEED_WORKERS
+	rootCmd.Flags().StringVarP(&serverURL, "url", "u", envOr("OCTA_SEED_URL", DefaultServerURL), "Server base URL")
+	rootCmd.Flags().StringVarP(&uploadSecret, "secret", "s", envOr("OCTA_SEED_SECRET", DefaultUploadSecret), "Upload secret (X-Secret-Key)")
+	rootCmd.Flags().IntVarP(&totalImages, "total", "t", envIntOr("OCTA_SEED_TOTAL", DefaultTotalImages), "Number of images to upload")
+	rootCmd.Flags().IntVarP(&workerCount, "workers", "w", envIntOr("OCTA_SEED_WORKERS", DefaultWorkerCount), "Concurrent uploads")
+	rootCmd.Flags().BoolVar(&offline, "offline", false, "Generate images locally instead of downloading from picsum.photos (fast, for large datasets)")
+	rootCmd.Flags().Int64Var(&seed, "seed", 0, "Random seed for reproducible keys and images (0 = random, printed at start)")
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func runSeed(cmd *cobra.Command, args []string) {
+	if totalImages < 1 || workerCount < 1 {
+		pterm.Fatal.Println("--total and --workers must be at least 1")
+	}
+	serverURL = strings.TrimRight(serverURL, "/")
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	source := "picsum.photos"
+	if offline {
+		source = "generated locally"
+	}
+
 	pterm.DefaultHeader.WithFullWidth().WithBackgroundStyle(pterm.NewStyle(pterm.BgLightMagenta)).WithTextStyle(pterm.NewStyle(pterm.FgBlack)).Println("OCTA ASSET SEEDER")
 	pterm.Println()
 
 	data := pterm.TableData{
-		{"Target Server", color.New(color.FgCyan).Sprint(ServerURL)},
-		{"Total Assets", color.New(color.FgYellow).Sprintf("%d images", TotalImages)},
-		{"Concurrency", color.New(color.FgYellow).Sprintf("%d workers", WorkerCount)},
-		{"Auth Secret", color.New(color.FgRed).Sprint("******")},
+		{"Target Server", fcolor.New(fcolor.FgCyan).Sprint(serverURL + "/upload")},
+		{"Total Assets", fcolor.New(fcolor.FgYellow).Sprintf("%d images", totalImages)},
+		{"Concurrency", fcolor.New(fcolor.FgYellow).Sprintf("%d workers", workerCount)},
+		{"Image Source", fcolor.New(fcolor.FgYellow).Sprint(source)},
+		{"Seed", fcolor.New(fcolor.FgYellow).Sprintf("%d (--seed %d to reproduce)", seed, seed)},
+		{"Auth Secret", fcolor.New(fcolor.FgRed).Sprint("******")},
 	}
 	_ = pterm.DefaultTable.WithBoxed().WithData(data).Render()
 	pterm.Println()
 
 	bar, _ := pterm.DefaultProgressbar.
-		WithTotal(TotalImages).
+		WithTotal(totalImages).
 		WithTitle("Seeding Assets...").
 		WithShowCount(true).
 		WithShowElapsedTime(true).
 		Start()
 
 	var wg sync.WaitGroup
-	jobs := make(chan int, TotalImages)
-	results := make(chan Result, TotalImages)
+	jobs := make(chan int, totalImages)
+	results := make(chan Result, totalImages)
 
 	// Start Workers
-	for w := 1; w <= WorkerCount; w++ {
+	for w := 1; w <= workerCount; w++ {
 		wg.Add(1)
 		go worker(w, jobs, results, &wg, bar)
 	}
 
-	for i := 1; i <= TotalImages; i++ {
+	for i := 1; i <= totalImages; i++ {
 		jobs <- i
 	}
 	close(jobs)
@@ -98,7 +151,7 @@ func main() {
 		pterm.Println()
 		pterm.Error.Println("Failure Report:")
 		for _, f := range failures {
-			fmt.Printf(" • %s: %v\n", color.RedString(f.Key), f.Error)
+			fmt.Printf(" • %s: %v\n", fcolor.RedString(f.Key), f.Error)
 		}
 	}
 
@@ -109,9 +162,18 @@ func worker(id int, jobs <-chan int, results chan<- Result, wg *sync.WaitGroup,
 	defer wg.Done()
 
 	for j := range jobs {
-		// Download Image
-		imgURL := fmt.Sprintf("https://picsum.photos/seed/%d/800/600", rand.Intn(10000)+j)
-		imgData, err := downloadImage(imgURL)
+		// Per-job source: output depends only on (seed, j), not on worker scheduling.
+		rng := rand.New(rand.NewSource(seed + int64(j)))
+
+		// Download (or generate) Image
+		var imgData []byte
+		var err error
+		if offline {
+			imgData, err = generateSeedImage(rng)
+		} else {
+			imgURL := fmt.Sprintf("https://picsum.photos/seed/%d/800/600", rng.Intn(10000)+j)
+			imgData, err = downloadImage(imgURL)
+		}
 
 		if err != nil {
 			bar.Increment() // The process is considered complete (even if it is incorrect).
@@ -121,14 +183,14 @@ func worker(id int, jobs <-chan int, results chan<- Result, wg *sync.WaitGroup,
 
 
 		var key string
-		name := names[rand.Intn(len(names))]
+		name := names[rng.Intn(len(names))]
 
-		if rand.Intn(100) < 25 {
+		if rng.Intn(100) < 25 {
 			// Root file: "hero-banner-12"
 			key = fmt.Sprintf("%s-%d", name, j)
 		} else {
 			// Folder file: "nature/mountain-12"
-			folder := folders[rand.Intn(len(folders))]
+			folder := folders[rng.Intn(len(folders))]
 			key = fmt.Sprintf("%s/%s-%d", folder, name, j)
 		}
 
@@ -159,6 +221,43 @@ func downloadImage(url string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
+// generateSeedImage draws a two-tone 800x600 JPEG from rng, no network needed.
+func generateSeedImage(rng *rand.Rand) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	c1 := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+	c2 := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+	split := rng.Intn(800)
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 800; x++ {
+			if x < split {
+				img.SetRGBA(x, y, c1)
+			} else {
+				img.SetRGBA(x, y, c2)
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envIntOr(name string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		return v
+	}
+	return fallback
+}
+
 func uploadToOcta(key string, data []byte) error {
 	body := new(bytes.Buffer)
 	writer := multipart.NewWriter(body)
@@ -173,13 +272,13 @@ func uploadToOcta(key string, data []byte) error {
 	_ = writer.WriteField("scale", "75")
 	writer.Close()
 
-	req, err := http.NewRequest("POST", ServerURL, body)
+	req, err := http.NewRequest("POST", serverURL+"/upload", body)
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("X-Secret-Key", UploadSecret)
+	req.Header.Set("X-Secret-Key", uploadSecret)
 
 	client := &http.Client{Timeout: 15 * time.Second}
 	resp, err := client.Do(req)